@@ -17,7 +17,24 @@ func main() {
 	database.Connect()
 
 	log.Println("Running migrations...")
-	if err := database.DB.AutoMigrate(&models.User{}, &models.Order{}); err != nil {
+	if err := database.DB.AutoMigrate(
+		&models.User{},
+		&models.Customer{},
+		&models.PhoneFlag{},
+		&models.PhoneFlagEvent{},
+		&models.Order{},
+		&models.OrderStop{},
+		&models.OrderEvent{},
+		&models.DriverLocation{},
+		&models.DriverLocationPoint{},
+		&models.OrderOffer{},
+		&models.IdempotencyKey{},
+		&models.Device{},
+		&models.OrderTemplate{},
+		&models.Tariff{},
+		&models.Zone{},
+		&models.ZoneFare{},
+	); err != nil {
 		log.Fatal("Migration failed:", err)
 	}
 	log.Println("Migrations completed successfully")
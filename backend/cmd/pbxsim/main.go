@@ -0,0 +1,72 @@
+// Command pbxsim plays an incoming call against the telephony webhook the way
+// the Asterisk PBX does: ringing, answered by an extension, then hangup.
+//
+//	go run ./cmd/pbxsim -caller "+7 701 123 45 67" -ext 101
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"taxi-fleet-backend/utils"
+)
+
+func main() {
+	_ = godotenv.Load()
+
+	url := flag.String("url", "http://localhost:8080/integrations/telephony/call", "webhook URL")
+	secret := flag.String("secret", os.Getenv("TELEPHONY_SECRET"), "shared secret (default $TELEPHONY_SECRET)")
+	caller := flag.String("caller", "77011234567", "caller ID")
+	ext := flag.String("ext", "101", "extension that answers")
+	ring := flag.Duration("ring", 2*time.Second, "time until the call is answered")
+	talk := flag.Duration("talk", 10*time.Second, "time until hangup")
+	flag.Parse()
+
+	if *secret == "" {
+		log.Fatal("No secret: set TELEPHONY_SECRET or pass -secret")
+	}
+
+	callID := fmt.Sprintf("sim-%d", time.Now().UnixNano())
+	send := func(event string) {
+		body, _ := json.Marshal(map[string]string{
+			"event":     event,
+			"call_id":   callID,
+			"caller":    *caller,
+			"extension": *ext,
+		})
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+		req, err := http.NewRequest(http.MethodPost, *url, bytes.NewReader(body))
+		if err != nil {
+			log.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Telephony-Timestamp", timestamp)
+		req.Header.Set("X-Telephony-Signature", "sha256="+utils.SignWebhook(*secret, timestamp, body))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Fatalf("%s: %v", event, err)
+		}
+		defer resp.Body.Close()
+		reply, _ := io.ReadAll(resp.Body)
+		log.Printf("%-8s -> %s %s", event, resp.Status, bytes.TrimSpace(reply))
+	}
+
+	log.Printf("Call %s from %s", callID, *caller)
+	send("ringing")
+	time.Sleep(*ring)
+	send("answered")
+	time.Sleep(*talk)
+	send("hangup")
+}
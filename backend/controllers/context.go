@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"taxi-fleet-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUser returns the authenticated user's ID and role set by AuthMiddleware.
+func currentUser(c *gin.Context) (uint, models.Role) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	id, _ := userID.(uint)
+	r, _ := role.(string)
+	return id, models.Role(r)
+}
+
+// parseOrderID reads the :id path parameter, answering 400 if it is malformed.
+func parseOrderID(c *gin.Context) (uint, bool) {
+	idInt, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID format"})
+		return 0, false
+	}
+	return uint(idInt), true
+}
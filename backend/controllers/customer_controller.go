@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	lookupLastOrders = 10
+	lookupFavourites = 5
+)
+
+type CustomerInput struct {
+	Name      string                   `json:"name"`
+	Phone     string                   `json:"phone" binding:"required"`
+	Notes     string                   `json:"notes"`
+	Language  string                   `json:"language" binding:"omitempty,oneof=ru kk en"`
+	Addresses []models.CustomerAddress `json:"addresses" binding:"max=10"`
+}
+
+func (in CustomerInput) apply(cust *models.Customer) bool {
+	phone := models.NormalizePhone(in.Phone)
+	if len(phone) < 5 {
+		return false
+	}
+	cust.Name = strings.TrimSpace(in.Name)
+	cust.Phone = phone
+	cust.Notes = in.Notes
+	cust.Language = in.Language
+	cust.Addresses = in.Addresses
+	return true
+}
+
+// GetCustomers searches customers by name or phone with ?q= (Dispatcher only)
+func GetCustomers(c *gin.Context) {
+	db := database.DB.Order("updated_at DESC").Limit(50)
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + likeEscaper.Replace(q) + "%"
+		if digits := models.NormalizePhone(q); digits != "" {
+			db = db.Where("name ILIKE ? OR phone LIKE ?", like, "%"+digits+"%")
+		} else {
+			db = db.Where("name ILIKE ?", like)
+		}
+	}
+
+	var customers []models.Customer
+	if err := db.Find(&customers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch customers"})
+		return
+	}
+	c.JSON(http.StatusOK, customers)
+}
+
+// CreateCustomer (Dispatcher only)
+func CreateCustomer(c *gin.Context) {
+	var input CustomerInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var customer models.Customer
+	if !input.apply(&customer) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phone number"})
+		return
+	}
+	var count int64
+	database.DB.Model(&models.Customer{}).Where("phone = ?", customer.Phone).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Customer with this phone already exists"})
+		return
+	}
+	if err := markBlacklisted(database.DB, &customer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create customer"})
+		return
+	}
+	if err := database.DB.Create(&customer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create customer"})
+		return
+	}
+	c.JSON(http.StatusOK, customer)
+}
+
+// UpdateCustomer (Dispatcher only)
+func UpdateCustomer(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID format"})
+		return
+	}
+
+	var input CustomerInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var customer models.Customer
+	if err := database.DB.First(&customer, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return
+	}
+	if !input.apply(&customer) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phone number"})
+		return
+	}
+	var count int64
+	database.DB.Model(&models.Customer{}).Where("phone = ? AND id <> ?", customer.Phone, customer.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Customer with this phone already exists"})
+		return
+	}
+	if err := markBlacklisted(database.DB, &customer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update customer"})
+		return
+	}
+	if err := database.DB.Save(&customer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update customer"})
+		return
+	}
+	c.JSON(http.StatusOK, customer)
+}
+
+type favouriteAddress struct {
+	Label   string   `json:"label,omitempty"`
+	Address string   `json:"address"`
+	Lat     *float64 `json:"lat"`
+	Lon     *float64 `json:"lon"`
+	Rides   int      `json:"rides"` // Orders starting or ending here
+}
+
+type customerLookup struct {
+	Customer           models.Customer    `json:"customer"`
+	Flag               *models.PhoneFlag  `json:"flag"` // Active blacklist flag on the phone
+	LastOrders         []models.Order     `json:"last_orders"`
+	FavouriteAddresses []favouriteAddress `json:"favourite_addresses"`
+}
+
+// LookupCustomer finds a customer by ?phone= together with their last orders and
+// favourite addresses, to prefill a new order (Dispatcher only)
+func LookupCustomer(c *gin.Context) {
+	phone := models.NormalizePhone(c.Query("phone"))
+	if phone == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "phone is required"})
+		return
+	}
+
+	flag, err := activePhoneFlag(database.DB, phone)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not look up customer"})
+		return
+	}
+	result, err := lookupCustomer(phone)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found", "phone": phone, "flag": flag})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not look up customer"})
+		return
+	}
+	result.Flag = flag
+	c.JSON(http.StatusOK, result)
+}
+
+func lookupCustomer(phone string) (*customerLookup, error) {
+	var result customerLookup
+	if err := database.DB.Where("phone = ?", phone).First(&result.Customer).Error; err != nil {
+		return nil, err
+	}
+
+	if err := database.DB.Where("customer_id = ?", result.Customer.ID).
+		Preload("Stops", orderedStops).
+		Order("created_at DESC").Limit(lookupLastOrders).
+		Find(&result.LastOrders).Error; err != nil {
+		return nil, err
+	}
+
+	// Saved addresses come first, then the most used pickup and dropoff points.
+	result.FavouriteAddresses = []favouriteAddress{}
+	seen := map[string]bool{}
+	for _, a := range result.Customer.Addresses {
+		result.FavouriteAddresses = append(result.FavouriteAddresses, favouriteAddress{Label: a.Label, Address: a.Address, Lat: a.Lat, Lon: a.Lon})
+		seen[a.Address] = true
+	}
+	var used []favouriteAddress
+	err := database.DB.Table("order_stops").
+		Select("order_stops.address, MAX(order_stops.lat) AS lat, MAX(order_stops.lon) AS lon, COUNT(*) AS rides").
+		Joins("JOIN orders ON orders.id = order_stops.order_id").
+		Where("orders.customer_id = ? AND order_stops.type IN ?", result.Customer.ID, []models.StopType{models.StopPickup, models.StopDropoff}).
+		Group("order_stops.address").
+		Order("rides DESC").
+		Limit(lookupFavourites + len(seen)).
+		Scan(&used).Error
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range result.FavouriteAddresses {
+		for _, u := range used {
+			if u.Address == a.Address {
+				result.FavouriteAddresses[i].Rides = u.Rides
+			}
+		}
+	}
+	added := 0
+	for _, u := range used {
+		if seen[u.Address] || added == lookupFavourites {
+			continue
+		}
+		result.FavouriteAddresses = append(result.FavouriteAddresses, u)
+		added++
+	}
+	return &result, nil
+}
+
+// customerForOrder returns the customer the new order is for: by ID, or by
+// phone, creating the customer on their first order.
+func customerForOrder(tx *gorm.DB, id *uint, phone, name string) (*models.Customer, error) {
+	var customer models.Customer
+	if id != nil {
+		if err := tx.First(&customer, *id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, newAPIError(http.StatusBadRequest, "Customer not found")
+			}
+			return nil, err
+		}
+		return &customer, nil
+	}
+
+	phone = models.NormalizePhone(phone)
+	if phone == "" {
+		return nil, nil
+	}
+	if len(phone) < 5 {
+		return nil, newAPIError(http.StatusBadRequest, "Invalid customer phone")
+	}
+	name = strings.TrimSpace(name)
+	err := tx.Where("phone = ?", phone).First(&customer).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		customer = models.Customer{Phone: phone, Name: name}
+		if err := markBlacklisted(tx, &customer); err != nil {
+			return nil, err
+		}
+		// A concurrent order for the same new phone may insert it first; use theirs.
+		res := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "phone"}}, DoNothing: true}).Create(&customer)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 0 {
+			customer = models.Customer{}
+			if err := tx.Where("phone = ?", phone).First(&customer).Error; err != nil {
+				return nil, err
+			}
+		}
+	case err != nil:
+		return nil, err
+	case customer.Name == "" && name != "":
+		customer.Name = name
+		if err := tx.Save(&customer).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &customer, nil
+}
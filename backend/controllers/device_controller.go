@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"net/http"
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/models"
+	"taxi-fleet-backend/notify"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+type RegisterDeviceInput struct {
+	Token    string `json:"token" binding:"required,max=4096"`
+	Platform string `json:"platform" binding:"required,oneof=android ios web"`
+}
+
+// RegisterDevice stores the push token of the calling user's device. A token
+// registered by another user before (shared phone) moves to the caller.
+func RegisterDevice(c *gin.Context) {
+	var input RegisterDeviceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, _ := currentUser(c)
+
+	device := models.Device{
+		UserID:     userID,
+		Token:      input.Token,
+		Platform:   input.Platform,
+		LastSeenAt: time.Now(),
+	}
+	err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "last_seen_at"}),
+	}).Create(&device).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not register device"})
+		return
+	}
+
+	database.DB.Where("token = ?", input.Token).First(&device)
+	c.JSON(http.StatusOK, device)
+}
+
+type UnregisterDeviceInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// UnregisterDevice stops pushes to a device of the calling user, e.g. on logout
+func UnregisterDevice(c *gin.Context) {
+	var input UnregisterDeviceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, _ := currentUser(c)
+
+	if err := database.DB.Where("user_id = ? AND token = ?", userID, input.Token).Delete(&models.Device{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not unregister device"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Device unregistered"})
+}
+
+// pushToDriver notifies the driver's devices if the order has a driver.
+func pushToDriver(driverID *uint, kind notify.PushKind, order *models.Order) {
+	if driverID == nil {
+		return
+	}
+	notify.DefaultPusher.NotifyDriver(*driverID, kind, order)
+}
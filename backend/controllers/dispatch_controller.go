@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/dispatch"
+	"taxi-fleet-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrderCandidates ranks free drivers by distance to the order's pickup point (Dispatcher only)
+func GetOrderCandidates(c *gin.Context) {
+	id, ok := parseOrderID(c)
+	if !ok {
+		return
+	}
+
+	limit := 10
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	var order models.Order
+	if err := database.DB.First(&order, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+	if order.Status.Terminal() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Order is already closed", "current_status": order.Status})
+		return
+	}
+	if !order.HasPickupPoint() {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Order has no pickup coordinates"})
+		return
+	}
+
+	candidates, err := dispatch.FindCandidates(database.DB, &order, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch candidates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, candidates)
+}
+
+type dispatchSettings struct {
+	Enabled             bool   `json:"enabled"`
+	Strategy            string `json:"strategy" binding:"required,oneof=nearest longest_idle round_robin"`
+	OfferTimeoutSeconds int    `json:"offer_timeout_seconds" binding:"required,min=5,max=600"`
+	RetryAfterSeconds   int    `json:"retry_after_seconds" binding:"omitempty,min=10,max=3600"` // Keeps the current value if empty
+}
+
+// GetDispatchSettings returns the auto-dispatch configuration (Dispatcher only)
+func GetDispatchSettings(c *gin.Context) {
+	cfg := dispatch.DefaultEngine.Config()
+	c.JSON(http.StatusOK, dispatchSettings{
+		Enabled:             cfg.Enabled,
+		Strategy:            cfg.Strategy,
+		OfferTimeoutSeconds: int(cfg.OfferTimeout / time.Second),
+		RetryAfterSeconds:   int(cfg.RetryAfter / time.Second),
+	})
+}
+
+// UpdateDispatchSettings switches auto-dispatch on or off and changes its strategy (Dispatcher only)
+func UpdateDispatchSettings(c *gin.Context) {
+	var input dispatchSettings
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	retryAfter := dispatch.DefaultEngine.Config().RetryAfter
+	if input.RetryAfterSeconds > 0 {
+		retryAfter = time.Duration(input.RetryAfterSeconds) * time.Second
+	}
+	err := dispatch.DefaultEngine.Configure(dispatch.Config{
+		Enabled:      input.Enabled,
+		Strategy:     input.Strategy,
+		OfferTimeout: time.Duration(input.OfferTimeoutSeconds) * time.Second,
+		RetryAfter:   retryAfter,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input.RetryAfterSeconds = int(retryAfter / time.Second)
+	c.JSON(http.StatusOK, input)
+}
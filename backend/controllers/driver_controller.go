@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"math"
 	"net/http"
 	"taxi-fleet-backend/database"
 	"taxi-fleet-backend/models"
+	"taxi-fleet-backend/realtime"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,36 +18,68 @@ func GetDrivers(c *gin.Context) {
 		return
 	}
 	type driverWithStats struct {
-		ID                uint   `json:"id"`
-		Name              string `json:"name"`
-		Phone             string `json:"phone"`
-		Role              string `json:"role"`
-		DriverStatus      string `json:"driver_status"`
-		AvatarURL         string `json:"avatar_url,omitempty"`
-		CreatedAt         string `json:"created_at"`
-		OrdersDone        int64  `json:"orders_done"`
-		OrdersInProgress  int64  `json:"orders_in_progress"`
+		ID               uint   `json:"id"`
+		Name             string `json:"name"`
+		Phone            string `json:"phone"`
+		Role             string `json:"role"`
+		DriverStatus     string `json:"driver_status"`
+		AvatarURL        string `json:"avatar_url,omitempty"`
+		CarMake          string `json:"car_make,omitempty"`
+		CarColor         string `json:"car_color,omitempty"`
+		CarPlate         string `json:"car_plate,omitempty"`
+		CreatedAt        string `json:"created_at"`
+		OrdersDone       int64  `json:"orders_done"`
+		OrdersInProgress int64  `json:"orders_in_progress"`
+		OrdersRejected   int    `json:"orders_rejected"`
+		// Averages over done orders, in minutes; null without data
+		AvgTimeToArrivalMin *float64 `json:"avg_time_to_arrival_min"`
+		AvgWaitingMin       *float64 `json:"avg_waiting_min"`
+		AvgRideMin          *float64 `json:"avg_ride_min"`
 	}
 	result := make([]driverWithStats, len(drivers))
 	for i, d := range drivers {
 		var done, inProgress int64
 		database.DB.Model(&models.Order{}).Where("driver_id = ? AND status = ?", d.ID, models.OrderDone).Count(&done)
 		database.DB.Model(&models.Order{}).Where("driver_id = ? AND status IN ?", d.ID, []models.OrderStatus{models.OrderAssigned, models.OrderAccepted, models.OrderInProgress}).Count(&inProgress)
+		var timing struct {
+			Arrival, Waiting, Ride *float64
+		}
+		database.DB.Model(&models.Order{}).
+			Select(`AVG(EXTRACT(EPOCH FROM arrived_at - accepted_at)) / 60 AS arrival,
+				AVG(EXTRACT(EPOCH FROM picked_up_at - arrived_at)) / 60 AS waiting,
+				AVG(EXTRACT(EPOCH FROM completed_at - picked_up_at)) / 60 AS ride`).
+			Where("driver_id = ? AND status = ?", d.ID, models.OrderDone).
+			Scan(&timing)
 		result[i] = driverWithStats{
-			ID:               d.ID,
-			Name:             d.Name,
-			Phone:            d.Phone,
-			Role:             string(d.Role),
-			DriverStatus:     string(d.DriverStatus),
-			AvatarURL:        d.AvatarURL,
-			CreatedAt:        d.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			OrdersDone:       done,
-			OrdersInProgress: inProgress,
+			ID:                  d.ID,
+			Name:                d.Name,
+			Phone:               d.Phone,
+			Role:                string(d.Role),
+			DriverStatus:        string(d.DriverStatus),
+			AvatarURL:           d.AvatarURL,
+			CarMake:             d.CarMake,
+			CarColor:            d.CarColor,
+			CarPlate:            d.CarPlate,
+			CreatedAt:           d.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			OrdersDone:          done,
+			OrdersInProgress:    inProgress,
+			OrdersRejected:      d.RejectedOrders,
+			AvgTimeToArrivalMin: roundMinutes(timing.Arrival),
+			AvgWaitingMin:       roundMinutes(timing.Waiting),
+			AvgRideMin:          roundMinutes(timing.Ride),
 		}
 	}
 	c.JSON(http.StatusOK, result)
 }
 
+func roundMinutes(m *float64) *float64 {
+	if m == nil {
+		return nil
+	}
+	r := math.Round(*m*10) / 10
+	return &r
+}
+
 type UpdateDriverStatusInput struct {
 	Status models.DriverStatus `json:"status" binding:"required,oneof=offline free busy"`
 }
@@ -80,5 +114,6 @@ func UpdateDriverStatus(c *gin.Context) {
 		return
 	}
 
+	realtime.DefaultHub.Publish(realtime.NewDriverStatusEvent(&user))
 	c.JSON(http.StatusOK, gin.H{"message": "Status updated", "status": user.DriverStatus})
 }
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/geo"
+	"taxi-fleet-backend/models"
+	"taxi-fleet-backend/pricing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TariffInput struct {
+	Name                  string  `json:"name" binding:"required"`
+	BaseFare              float64 `json:"base_fare"`
+	PerKm                 float64 `json:"per_km"`
+	PerMinute             float64 `json:"per_minute"`
+	MinimumFare           float64 `json:"minimum_fare"`
+	FreeWaitingMinutes    float64 `json:"free_waiting_minutes"`
+	WaitingPerMinute      float64 `json:"waiting_per_minute"`
+	NightSurchargePercent float64 `json:"night_surcharge_percent"`
+	NightStart            string  `json:"night_start"` // HH:MM
+	NightEnd              string  `json:"night_end"`   // HH:MM
+	AirportPickupFee      float64 `json:"airport_pickup_fee"`
+	AirportDropoffFee     float64 `json:"airport_dropoff_fee"`
+	Timezone              string  `json:"timezone"`
+	IsDefault             bool    `json:"is_default"`
+}
+
+func (in TariffInput) apply(t *models.Tariff) {
+	t.Name = in.Name
+	t.BaseFare = in.BaseFare
+	t.PerKm = in.PerKm
+	t.PerMinute = in.PerMinute
+	t.MinimumFare = in.MinimumFare
+	t.FreeWaitingMinutes = in.FreeWaitingMinutes
+	t.WaitingPerMinute = in.WaitingPerMinute
+	t.NightSurchargePercent = in.NightSurchargePercent
+	t.NightStart = in.NightStart
+	t.NightEnd = in.NightEnd
+	t.AirportPickupFee = in.AirportPickupFee
+	t.AirportDropoffFee = in.AirportDropoffFee
+	t.Timezone = in.Timezone
+	t.IsDefault = in.IsDefault
+}
+
+// GetTariffs lists all tariffs (Dispatcher only)
+func GetTariffs(c *gin.Context) {
+	var tariffs []models.Tariff
+	if err := database.DB.Order("id ASC").Find(&tariffs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch tariffs"})
+		return
+	}
+	c.JSON(http.StatusOK, tariffs)
+}
+
+// CreateTariff (Dispatcher only)
+func CreateTariff(c *gin.Context) {
+	var input TariffInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tariff models.Tariff
+	input.apply(&tariff)
+	saveTariff(c, &tariff)
+}
+
+// UpdateTariff replaces a tariff's prices. Quotes already stored on orders are not changed (Dispatcher only)
+func UpdateTariff(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tariff ID format"})
+		return
+	}
+
+	var input TariffInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tariff models.Tariff
+	if err := database.DB.First(&tariff, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tariff not found"})
+		return
+	}
+	input.apply(&tariff)
+	saveTariff(c, &tariff)
+}
+
+// saveTariff validates and stores the tariff. Only one tariff can be the default.
+func saveTariff(c *gin.Context, tariff *models.Tariff) {
+	if err := tariff.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(tariff).Error; err != nil {
+			return err
+		}
+		if !tariff.IsDefault {
+			return nil
+		}
+		return tx.Model(&models.Tariff{}).Where("id <> ? AND is_default = ?", tariff.ID, true).
+			Update("is_default", false).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save tariff"})
+		return
+	}
+	c.JSON(http.StatusOK, tariff)
+}
+
+type EstimateFareInput struct {
+	Stops    []OrderStopInput `json:"stops" binding:"required,min=2,max=10,dive"`
+	TariffID *uint            `json:"tariff_id"` // Default tariff if empty
+	At       *time.Time       `json:"at"`        // Pickup time, now if empty
+}
+
+// EstimateFare quotes a route before the order is created. Stops without
+// coordinates are geocoded from their addresses (Dispatcher only)
+func EstimateFare(c *gin.Context) {
+	var input EstimateFareInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stops := make([]models.OrderStop, len(input.Stops))
+	for i, st := range input.Stops {
+		stops[i] = models.OrderStop{Address: st.Address, Lat: st.Lat, Lon: st.Lon}
+	}
+	geo.LocateStops(c.Request.Context(), stops)
+
+	start := time.Now()
+	if input.At != nil {
+		start = *input.At
+	}
+	quote, err := pricing.QuoteRoute(database.DB, input.TariffID, stops, start)
+	switch {
+	case errors.Is(err, pricing.ErrNoTariff) && input.TariffID != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tariff not found"})
+		return
+	case errors.Is(err, pricing.ErrNoTariff):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "No default tariff configured"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load tariff"})
+		return
+	}
+	if quote.Fare == nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Could not locate every stop of the route"})
+		return
+	}
+	c.JSON(http.StatusOK, quote.Fare)
+}
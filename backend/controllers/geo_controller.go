@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"taxi-fleet-backend/geo"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GeoSearch looks up addresses and landmarks. With autocomplete=true the
+// query is treated as a prefix the user is still typing.
+func GeoSearch(c *gin.Context) {
+	if geo.DefaultGeocoder == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Geocoding is not configured"})
+		return
+	}
+
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	limit := 10
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 50"})
+			return
+		}
+		limit = n
+	}
+
+	var places []geo.Place
+	var err error
+	if c.Query("autocomplete") == "true" {
+		places, err = geo.DefaultGeocoder.Autocomplete(c.Request.Context(), query, limit)
+	} else {
+		places, err = geo.DefaultGeocoder.Search(c.Request.Context(), query, limit)
+	}
+	if err != nil {
+		log.Printf("GeoSearch: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Geocoding failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, places)
+}
+
+// GeoReverse returns the place closest to ?lat=&lon=.
+func GeoReverse(c *gin.Context) {
+	if geo.DefaultGeocoder == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Geocoding is not configured"})
+		return
+	}
+
+	lat, err1 := strconv.ParseFloat(c.Query("lat"), 64)
+	lon, err2 := strconv.ParseFloat(c.Query("lon"), 64)
+	if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Valid lat and lon are required"})
+		return
+	}
+
+	place, err := geo.DefaultGeocoder.Reverse(c.Request.Context(), geo.Point{Lat: lat, Lon: lon})
+	if errors.Is(err, geo.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No place found"})
+		return
+	}
+	if err != nil {
+		log.Printf("GeoReverse: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Geocoding failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, place)
+}
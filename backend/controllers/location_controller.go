@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"net/http"
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// trackRetention is how long track history is kept per driver.
+const trackRetention = 24 * time.Hour
+
+type LocationPointInput struct {
+	Lat       float64   `json:"lat" binding:"min=-90,max=90"`
+	Lon       float64   `json:"lon" binding:"min=-180,max=180"`
+	Accuracy  float64   `json:"accuracy" binding:"min=0"`
+	Speed     float64   `json:"speed" binding:"min=0"`
+	Heading   float64   `json:"heading" binding:"min=0,max=360"`
+	Timestamp time.Time `json:"timestamp" binding:"required"`
+}
+
+type ReportLocationInput struct {
+	Points []LocationPointInput `json:"points" binding:"required,min=1,max=100,dive"`
+}
+
+// ReportLocation stores a batch of GPS fixes from the calling driver
+func ReportLocation(c *gin.Context) {
+	driverID, _ := currentUser(c)
+
+	var input ReportLocationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	points := make([]models.DriverLocationPoint, 0, len(input.Points))
+	var latest *LocationPointInput
+	for i := range input.Points {
+		p := &input.Points[i]
+		// Devices with a wrong clock must not push fixes into the future.
+		if p.Timestamp.After(now) {
+			p.Timestamp = now
+		}
+		points = append(points, models.DriverLocationPoint{
+			DriverID:   driverID,
+			Lat:        p.Lat,
+			Lon:        p.Lon,
+			Accuracy:   p.Accuracy,
+			Speed:      p.Speed,
+			Heading:    p.Heading,
+			RecordedAt: p.Timestamp,
+		})
+		if latest == nil || p.Timestamp.After(latest.Timestamp) {
+			latest = p
+		}
+	}
+
+	location := models.DriverLocation{
+		DriverID:   driverID,
+		Lat:        latest.Lat,
+		Lon:        latest.Lon,
+		Accuracy:   latest.Accuracy,
+		Speed:      latest.Speed,
+		Heading:    latest.Heading,
+		RecordedAt: latest.Timestamp,
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&points).Error; err != nil {
+			return err
+		}
+		// Batches may arrive out of order; only move the latest fix forward.
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "driver_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"lat", "lon", "accuracy", "speed", "heading", "recorded_at", "updated_at"}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "driver_locations.recorded_at < excluded.recorded_at"},
+			}},
+		}).Create(&location).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("driver_id = ? AND recorded_at < ?", driverID, now.Add(-trackRetention)).
+			Delete(&models.DriverLocationPoint{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save location"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Location saved", "accepted": len(points)})
+}
+
+type driverLocationView struct {
+	DriverID     uint                `json:"driver_id"`
+	Name         string              `json:"name"`
+	DriverStatus models.DriverStatus `json:"driver_status"`
+	Lat          float64             `json:"lat"`
+	Lon          float64             `json:"lon"`
+	Accuracy     float64             `json:"accuracy"`
+	Speed        float64             `json:"speed"`
+	Heading      float64             `json:"heading"`
+	RecordedAt   time.Time           `json:"recorded_at"`
+	AgeSeconds   int64               `json:"age_seconds"`
+	Stale        bool                `json:"stale"`
+}
+
+// GetDriverLocations returns the latest fix of every online driver (Dispatcher only)
+func GetDriverLocations(c *gin.Context) {
+	var drivers []models.User
+	if err := database.DB.Where("role = ? AND driver_status <> ?", models.RoleDriver, models.StatusOffline).Find(&drivers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch drivers"})
+		return
+	}
+
+	ids := make([]uint, len(drivers))
+	for i, d := range drivers {
+		ids[i] = d.ID
+	}
+	var locations []models.DriverLocation
+	if len(ids) > 0 {
+		if err := database.DB.Where("driver_id IN ?", ids).Find(&locations).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch locations"})
+			return
+		}
+	}
+	byDriver := make(map[uint]models.DriverLocation, len(locations))
+	for _, l := range locations {
+		byDriver[l.DriverID] = l
+	}
+
+	now := time.Now()
+	result := make([]driverLocationView, 0, len(locations))
+	for _, d := range drivers {
+		l, ok := byDriver[d.ID]
+		if !ok {
+			continue
+		}
+		age := now.Sub(l.RecordedAt)
+		result = append(result, driverLocationView{
+			DriverID:     d.ID,
+			Name:         d.Name,
+			DriverStatus: d.DriverStatus,
+			Lat:          l.Lat,
+			Lon:          l.Lon,
+			Accuracy:     l.Accuracy,
+			Speed:        l.Speed,
+			Heading:      l.Heading,
+			RecordedAt:   l.RecordedAt,
+			AgeSeconds:   int64(age.Seconds()),
+			Stale:        age > models.StaleLocationAfter,
+		})
+	}
+
+	c.JSON(http.StatusOK, result)
+}
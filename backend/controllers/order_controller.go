@@ -1,21 +1,46 @@
 package controllers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/dispatch"
+	"taxi-fleet-backend/geo"
 	"taxi-fleet-backend/models"
+	"taxi-fleet-backend/notify"
+	"taxi-fleet-backend/pricing"
+	"taxi-fleet-backend/realtime"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+type OrderStopInput struct {
+	Address   string   `json:"address" binding:"required"`
+	Lat       *float64 `json:"lat" binding:"required_with=Lon,omitempty,min=-90,max=90"`
+	Lon       *float64 `json:"lon" binding:"required_with=Lat,omitempty,min=-180,max=180"`
+	Entrance  string   `json:"entrance"`
+	Apartment string   `json:"apartment"`
+	Notes     string   `json:"notes"`
+}
+
 type CreateOrderInput struct {
-	FromAddress string `json:"from_address" binding:"required"`
-	ToAddress   string `json:"to_address" binding:"required"`
-	Comment     string `json:"comment"`
-	DriverID    *uint  `json:"driver_id"` // Optional, can be assigned later
+	FromAddress   string           `json:"from_address" binding:"required_without=Stops"`
+	ToAddress     string           `json:"to_address" binding:"required_without=Stops"`
+	PickupLat     *float64         `json:"pickup_lat" binding:"required_with=PickupLon,omitempty,min=-90,max=90"`
+	PickupLon     *float64         `json:"pickup_lon" binding:"required_with=PickupLat,omitempty,min=-180,max=180"`
+	Stops         []OrderStopInput `json:"stops" binding:"omitempty,min=2,max=10,dive"` // Pickup, optional waypoints, dropoff; overrides the addresses above
+	Comment       string           `json:"comment"`
+	DriverID      *uint            `json:"driver_id"`      // Optional, can be assigned later; reserves the driver for scheduled orders
+	Force         bool             `json:"force"`          // Assign even if the driver is busy with another order
+	ScheduledAt   *time.Time       `json:"scheduled_at"`   // Optional pickup time for pre-booked orders
+	TariffID      *uint            `json:"tariff_id"`      // Tariff to quote with, the default one if empty
+	CustomerID    *uint            `json:"customer_id"`    // Known customer, takes precedence over the phone
+	CustomerPhone string           `json:"customer_phone"` // Creates the customer on their first order
+	CustomerName  string           `json:"customer_name"`
 }
 
 // CreateOrder (Dispatcher only)
@@ -25,58 +50,210 @@ func CreateOrder(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if input.ScheduledAt != nil && !input.ScheduledAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scheduled_at must be in the future"})
+		return
+	}
 
 	order := models.Order{
 		FromAddress: input.FromAddress,
 		ToAddress:   input.ToAddress,
+		PickupLat:   input.PickupLat,
+		PickupLon:   input.PickupLon,
 		Comment:     input.Comment,
-		Status:      models.OrderNew,
 		DriverID:    input.DriverID,
+		ScheduledAt: input.ScheduledAt,
+	}
+	if len(input.Stops) > 0 {
+		stops := make([]models.OrderStop, len(input.Stops))
+		for i, st := range input.Stops {
+			stops[i] = models.OrderStop{
+				Address:   st.Address,
+				Lat:       st.Lat,
+				Lon:       st.Lon,
+				Entrance:  st.Entrance,
+				Apartment: st.Apartment,
+				Notes:     st.Notes,
+			}
+		}
+		order.Stops = models.BuildRoute(stops)
+	} else {
+		order.Stops = models.SimpleRoute(order.FromAddress, order.ToAddress, order.PickupLat, order.PickupLon)
+	}
+	geo.LocateStops(c.Request.Context(), order.Stops)
+	err := pricing.QuoteOrder(database.DB, &order, input.TariffID)
+	switch {
+	case errors.Is(err, pricing.ErrNoTariff):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tariff not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load tariff"})
+		return
 	}
 
-	if input.DriverID != nil {
-		order.Status = models.OrderAssigned
+	status := models.OrderNew
+	switch {
+	case input.ScheduledAt != nil:
+		status = models.OrderScheduled
+	case input.DriverID != nil:
+		status = models.OrderAssigned
+	}
+	if err := models.OrderFlow.Apply(&order, models.RoleDispatcher, status); err != nil {
+		respondTransitionError(c, err)
+		return
 	}
 
-	if err := database.DB.Create(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create order"})
+	actorID, actorRole := currentUser(c)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		customer, err := customerForOrder(tx, input.CustomerID, input.CustomerPhone, input.CustomerName)
+		if err != nil {
+			return err
+		}
+		if customer != nil {
+			flag, err := activePhoneFlag(tx, customer.Phone)
+			if err != nil {
+				return err
+			}
+			switch {
+			case flag == nil:
+			case flag.Severity == models.FlagBlock:
+				return &apiError{Status: http.StatusForbidden, Body: gin.H{
+					"error":    "Customer phone is blacklisted",
+					"flag_id":  flag.ID,
+					"severity": flag.Severity,
+					"reason":   flag.Reason,
+				}}
+			default:
+				order.Warnings = append(order.Warnings, "Customer phone is flagged: "+flag.Reason)
+			}
+			order.CustomerID = &customer.ID
+			order.Customer = customer
+		}
+		if order.DriverID != nil {
+			driver, err := lockDriver(tx, *order.DriverID)
+			if err != nil {
+				return err
+			}
+			if order.Status == models.OrderAssigned {
+				if err := checkDriverAvailable(tx, driver, 0, input.Force); err != nil {
+					return err
+				}
+			}
+		}
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		return tx.Create(models.NewOrderEvent(&order, actorID, actorRole, "", nil, "")).Error
+	})
+	if err != nil {
+		respondError(c, err, "Could not create order")
 		return
 	}
 
-	c.JSON(http.StatusOK, order)
+	realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderCreated, &order, nil))
+	switch order.Status {
+	case models.OrderNew:
+		dispatch.DefaultEngine.Notify()
+	case models.OrderAssigned:
+		pushToDriver(order.DriverID, notify.PushAssigned, &order)
+	}
+	respondOrder(c, &order)
 }
 
-// GetOrders lists orders based on role
+// GetOrders lists active orders based on role. Accepts the same filters as
+// GetOrderHistory but returns every match unless limit or cursor is given;
+// paging info is returned in X-Total-Count and X-Next-Cursor.
 func GetOrders(c *gin.Context) {
-	userID, _ := c.Get("userID")
-	role, _ := c.Get("role")
-
-	var orders []models.Order
+	query, err := parseOrderQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if role == string(models.RoleDispatcher) {
-		if err := database.DB.Preload("Driver").Where("status NOT IN ?", []models.OrderStatus{models.OrderDone, models.OrderCancelled}).Find(&orders).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch orders"})
-			return
-		}
-	} else if role == string(models.RoleDriver) {
-		if err := database.DB.Where("driver_id = ? AND status IN ?", userID, []models.OrderStatus{models.OrderAssigned, models.OrderAccepted, models.OrderInProgress}).Find(&orders).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch orders"})
-			return
+	if len(query.Statuses) == 0 {
+		query.Statuses = []models.OrderStatus{models.OrderNew, models.OrderAssigned, models.OrderAccepted, models.OrderInProgress}
+		// Drivers also see the pre-booked orders reserved for them.
+		if _, role := currentUser(c); role == models.RoleDriver {
+			query.Statuses = append(query.Statuses, models.OrderScheduled)
 		}
 	}
 
-	c.JSON(http.StatusOK, orders)
+	db, ok := scopeOrdersForUser(c, &query)
+	if !ok {
+		return
+	}
+
+	page, err := query.fetch(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch orders"})
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if page.NextCursor != "" {
+		c.Header("X-Next-Cursor", page.NextCursor)
+	}
+	c.JSON(http.StatusOK, page.Items)
+}
+
+// GetOrderHistory lists orders of any status with filtering, sorting and cursor pagination.
+// Drivers only see their own orders.
+func GetOrderHistory(c *gin.Context) {
+	query, err := parseOrderQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultOrderPageSize
+	}
+
+	db, ok := scopeOrdersForUser(c, &query)
+	if !ok {
+		return
+	}
+
+	page, err := query.fetch(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch orders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// scopeOrdersForUser restricts drivers to their own orders.
+func scopeOrdersForUser(c *gin.Context, query *orderQuery) (*gorm.DB, bool) {
+	userID, role := currentUser(c)
+	switch role {
+	case models.RoleDispatcher:
+		return database.DB, true
+	case models.RoleDriver:
+		query.DriverID = &userID
+		return database.DB, true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	return nil, false
 }
 
 type AssignDriverInput struct {
-	DriverID uint `json:"driver_id" binding:"required"`
+	DriverID uint   `json:"driver_id" binding:"required"`
+	Force    bool   `json:"force"` // Assign even if the driver is busy with another order
+	Note     string `json:"note"`
+	Version  *uint  `json:"version"` // Alternative to If-Match
 }
 
-// AssignDriver assigns a driver to an order (Dispatcher only)
+// AssignDriver assigns or reassigns a driver to an order (Dispatcher only).
+// The previous driver is released, and the new one must be online and, unless
+// forced, have no other active order. On a scheduled order it only reserves
+// the driver, who is checked when the order is released.
 func AssignDriver(c *gin.Context) {
-	idParam := c.Param("id")
-	log.Printf("AssignDriver called with order ID: %s", idParam)
-	
+	id, ok := parseOrderID(c)
+	if !ok {
+		return
+	}
+	log.Printf("AssignDriver called with order ID: %d", id)
+
 	var input AssignDriverInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		log.Printf("Error binding JSON: %v", err)
@@ -84,133 +261,499 @@ func AssignDriver(c *gin.Context) {
 		return
 	}
 
-	log.Printf("AssignDriver: driver_id=%d, order_id=%s", input.DriverID, idParam)
+	log.Printf("AssignDriver: driver_id=%d, order_id=%d, force=%t", input.DriverID, id, input.Force)
 
-	// Convert string ID to uint for GORM query
-	idInt, err := strconv.ParseUint(idParam, 10, 32)
+	version, ok := expectedVersion(c, input.Version, true)
+	if !ok {
+		return
+	}
+
+	actorID, actorRole := currentUser(c)
+	var order *models.Order
+	var driverBefore *uint
+	var released *models.User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if order, err = lockOrder(tx, id); err != nil {
+			return err
+		}
+		if err := checkVersion(order, version); err != nil {
+			return err
+		}
+		fromStatus := order.Status
+		driverBefore = order.DriverID
+		to := models.OrderAssigned
+		if fromStatus == models.OrderScheduled {
+			to = models.OrderScheduled
+		}
+		if err := models.OrderFlow.Can(models.RoleDispatcher, fromStatus, to); err != nil {
+			return err
+		}
+		if driverBefore != nil && *driverBefore == input.DriverID && fromStatus == to {
+			return newAPIError(http.StatusConflict, "Driver is already assigned to this order")
+		}
+
+		driver, err := lockDriver(tx, input.DriverID)
+		if err != nil {
+			return err
+		}
+		if to == models.OrderScheduled {
+			order.DriverID = &driver.ID
+			order.RemindedAt = nil
+			order.UpdatedAt = time.Now()
+			if err := tx.Save(order).Error; err != nil {
+				return err
+			}
+			return tx.Create(models.NewOrderEvent(order, actorID, actorRole, fromStatus, driverBefore, input.Note)).Error
+		}
+		if err := checkDriverAvailable(tx, driver, order.ID, input.Force); err != nil {
+			return err
+		}
+
+		if driverBefore != nil && *driverBefore != driver.ID {
+			if released, err = releaseDriver(tx, *driverBefore, order.ID); err != nil {
+				return err
+			}
+		}
+
+		order.DriverID = &driver.ID
+		order.Status = models.OrderAssigned
+		if err := clearDriverProgress(tx, order); err != nil {
+			return err
+		}
+		order.UpdatedAt = time.Now()
+		if err := tx.Save(order).Error; err != nil {
+			return err
+		}
+		if err := dispatch.WithdrawOffers(tx, order.ID); err != nil {
+			return err
+		}
+		return tx.Create(models.NewOrderEvent(order, actorID, actorRole, fromStatus, driverBefore, input.Note)).Error
+	})
 	if err != nil {
-		log.Printf("Error parsing order ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID format"})
+		log.Printf("AssignDriver: order %d: %v", id, err)
+		respondError(c, err, "Could not assign driver")
 		return
 	}
-	id := uint(idInt)
 
-	var order models.Order
-	if err := database.DB.First(&order, id).Error; err != nil {
-		log.Printf("Order not found: ID=%d, error=%v", id, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	database.DB.Preload("Driver").First(order, order.ID)
+	realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderAssigned, order, driverBefore))
+	if released != nil {
+		realtime.DefaultHub.Publish(realtime.NewDriverStatusEvent(released))
+	}
+	pushToDriver(order.DriverID, notify.PushAssigned, order)
+	if driverBefore != nil && *driverBefore != *order.DriverID {
+		pushToDriver(driverBefore, notify.PushUnassigned, order)
+	}
+	respondOrder(c, order)
+}
+
+type UnassignDriverInput struct {
+	Note    string `json:"note"`
+	Version *uint  `json:"version"`
+}
+
+// UnassignDriver takes the driver off an order and returns it to the queue, or
+// drops the reservation of a scheduled order (Dispatcher only)
+func UnassignDriver(c *gin.Context) {
+	id, ok := parseOrderID(c)
+	if !ok {
+		return
+	}
+
+	var input UnassignDriverInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	version, ok := expectedVersion(c, input.Version, false)
+	if !ok {
+		return
+	}
+
+	actorID, actorRole := currentUser(c)
+	var order *models.Order
+	var driverBefore *uint
+	var released *models.User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if order, err = lockOrder(tx, id); err != nil {
+			return err
+		}
+		if err := checkVersion(order, version); err != nil {
+			return err
+		}
+		fromStatus := order.Status
+		driverBefore = order.DriverID
+		to := models.OrderNew
+		if fromStatus == models.OrderScheduled {
+			if driverBefore == nil {
+				return newAPIError(http.StatusConflict, "No driver is reserved for this order")
+			}
+			to = models.OrderScheduled
+		}
+		if err := models.OrderFlow.Apply(order, models.RoleDispatcher, to); err != nil {
+			return err
+		}
+		if driverBefore != nil && to == models.OrderNew {
+			if released, err = releaseDriver(tx, *driverBefore, order.ID); err != nil {
+				return err
+			}
+		}
+
+		order.DriverID = nil
+		if err := clearDriverProgress(tx, order); err != nil {
+			return err
+		}
+		order.UpdatedAt = time.Now()
+		if err := tx.Save(order).Error; err != nil {
+			return err
+		}
+		if err := dispatch.WithdrawOffers(tx, order.ID); err != nil {
+			return err
+		}
+		return tx.Create(models.NewOrderEvent(order, actorID, actorRole, fromStatus, driverBefore, input.Note)).Error
+	})
+	if err != nil {
+		respondError(c, err, "Could not unassign driver")
 		return
 	}
-	
-	log.Printf("Order found: ID=%d, Status=%s", order.ID, order.Status)
 
-	if order.Status == models.OrderCancelled || order.Status == models.OrderDone {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot assign driver to completed or cancelled order"})
+	realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderStatusChanged, order, driverBefore))
+	if released != nil {
+		realtime.DefaultHub.Publish(realtime.NewDriverStatusEvent(released))
+	}
+	pushToDriver(driverBefore, notify.PushUnassigned, order)
+	if order.Status == models.OrderNew {
+		dispatch.DefaultEngine.Notify()
+	}
+	respondOrder(c, order)
+}
+
+type RescheduleOrderInput struct {
+	ScheduledAt time.Time `json:"scheduled_at" binding:"required"`
+	Note        string    `json:"note"`
+	Version     *uint     `json:"version"` // Alternative to If-Match
+}
+
+// RescheduleOrder moves the pickup time of a scheduled order and re-quotes it
+// for the new time (Dispatcher only)
+func RescheduleOrder(c *gin.Context) {
+	id, ok := parseOrderID(c)
+	if !ok {
 		return
 	}
 
-	var driver models.User
-	if err := database.DB.First(&driver, input.DriverID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Driver not found"})
+	var input RescheduleOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !input.ScheduledAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scheduled_at must be in the future"})
 		return
 	}
-	if driver.Role != models.RoleDriver {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User is not a driver"})
+	version, ok := expectedVersion(c, input.Version, true)
+	if !ok {
 		return
 	}
 
-	order.DriverID = &input.DriverID
-	order.Status = models.OrderAssigned
-	order.UpdatedAt = time.Now()
+	actorID, actorRole := currentUser(c)
+	var order *models.Order
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if order, err = lockOrder(tx, id); err != nil {
+			return err
+		}
+		if err := checkVersion(order, version); err != nil {
+			return err
+		}
+		fromStatus := order.Status
+		if err := models.OrderFlow.Apply(order, models.RoleDispatcher, models.OrderScheduled); err != nil {
+			return err
+		}
 
-	if err := database.DB.Save(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not assign driver"})
+		order.ScheduledAt = &input.ScheduledAt
+		order.RemindedAt = nil
+		if order.TariffID != nil {
+			var stops []models.OrderStop
+			if err := tx.Where("order_id = ?", order.ID).Order("position ASC").Find(&stops).Error; err != nil {
+				return err
+			}
+			quote, err := pricing.QuoteRoute(tx, order.TariffID, stops, input.ScheduledAt)
+			switch {
+			case err == nil:
+				order.QuotedFare = quote.Fare
+			case !errors.Is(err, pricing.ErrNoTariff):
+				return err
+			}
+		}
+		order.UpdatedAt = time.Now()
+		if err := tx.Save(order).Error; err != nil {
+			return err
+		}
+		return tx.Create(models.NewOrderEvent(order, actorID, actorRole, fromStatus, order.DriverID, input.Note)).Error
+	})
+	if err != nil {
+		respondError(c, err, "Could not reschedule order")
 		return
 	}
 
-	database.DB.Preload("Driver").First(&order, order.ID)
-	c.JSON(http.StatusOK, order)
+	realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderRescheduled, order, nil))
+	respondOrder(c, order)
 }
 
 type UpdateOrderStatusInput struct {
-	Status models.OrderStatus `json:"status" binding:"required"`
+	Status  models.OrderStatus `json:"status" binding:"required"`
+	Note    string             `json:"note"`
+	Version *uint              `json:"version"` // Alternative to If-Match
 }
 
-// UpdateOrderStatus handles status transitions
+// UpdateOrderStatus handles status transitions. The order and its driver are
+// locked and updated in one transaction, so they never disagree.
 func UpdateOrderStatus(c *gin.Context) {
-	idParam := c.Param("id")
+	id, ok := parseOrderID(c)
+	if !ok {
+		return
+	}
+
 	var input UpdateOrderStatusInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Convert string ID to uint for GORM query
-	idInt, err := strconv.ParseUint(idParam, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID format"})
+	switch input.Status {
+	case models.OrderAssigned:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use the assign endpoint to assign a driver"})
+		return
+	case models.OrderNew:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use the unassign (dispatcher) or reject (driver) endpoint to return an order to the queue"})
 		return
 	}
-	id := uint(idInt)
 
-	var order models.Order
-	if err := database.DB.First(&order, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	version, ok := expectedVersion(c, input.Version, true)
+	if !ok {
 		return
 	}
 
-	role, _ := c.Get("role")
-	userID, _ := c.Get("userID")
+	userID, actor := currentUser(c)
+	var order *models.Order
+	var driverChanged *models.User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if order, err = lockOrder(tx, id); err != nil {
+			return err
+		}
+		if actor == models.RoleDriver && (order.DriverID == nil || *order.DriverID != userID) {
+			return newAPIError(http.StatusForbidden, "Not your order")
+		}
+		if err := checkVersion(order, version); err != nil {
+			return err
+		}
 
-	updateDriverStatus := func(driverID uint, status models.DriverStatus) {
-		var driver models.User
-		if err := database.DB.First(&driver, driverID).Error; err == nil {
-			driver.DriverStatus = status
-			database.DB.Save(&driver)
+		fromStatus := order.Status
+		if err := models.OrderFlow.Apply(order, actor, input.Status); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		switch order.Status {
+		case models.OrderAccepted:
+			order.AcceptedAt = &now
+		case models.OrderInProgress:
+			order.PickedUpAt = &now
+		case models.OrderDone:
+			order.CompletedAt = &now
 		}
-	}
 
-	if role == string(models.RoleDispatcher) {
-		if input.Status == models.OrderCancelled {
-			order.Status = models.OrderCancelled
-			if order.DriverID != nil {
-				updateDriverStatus(*order.DriverID, models.StatusFree)
+		if order.DriverID != nil {
+			switch order.Status {
+			case models.OrderInProgress:
+				driver, err := lockDriver(tx, *order.DriverID)
+				if err != nil {
+					return err
+				}
+				if driver.DriverStatus != models.StatusBusy {
+					driver.DriverStatus = models.StatusBusy
+					if err := tx.Save(driver).Error; err != nil {
+						return err
+					}
+					driverChanged = driver
+				}
+			case models.OrderDone, models.OrderCancelled:
+				if driverChanged, err = releaseDriver(tx, *order.DriverID, order.ID); err != nil {
+					return err
+				}
 			}
-		} else {
-			order.Status = input.Status
 		}
-	} else {
-		if order.DriverID == nil || *order.DriverID != userID.(uint) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Not your order"})
-			return
+
+		if order.Status == models.OrderDone {
+			if err := settleFare(tx, order, now); err != nil {
+				return err
+			}
 		}
 
-		switch input.Status {
+		order.UpdatedAt = now
+		if err := tx.Save(order).Error; err != nil {
+			return err
+		}
+		switch order.Status {
 		case models.OrderAccepted:
-			if order.Status == models.OrderAssigned {
-				order.Status = models.OrderAccepted
-			}
-		case models.OrderInProgress:
-			if order.Status == models.OrderAccepted {
-				order.Status = models.OrderInProgress
-				updateDriverStatus(*order.DriverID, models.StatusBusy)
+			if _, err := dispatch.ResolveOffer(tx, order.ID, *order.DriverID, models.OfferAccepted); err != nil {
+				return err
 			}
-		case models.OrderDone:
-			if order.Status == models.OrderInProgress {
-				order.Status = models.OrderDone
-				updateDriverStatus(*order.DriverID, models.StatusFree)
+		case models.OrderCancelled:
+			if err := dispatch.WithdrawOffers(tx, order.ID); err != nil {
+				return err
 			}
-		default:
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status transition"})
-			return
 		}
+		return tx.Create(models.NewOrderEvent(order, userID, actor, fromStatus, order.DriverID, input.Note)).Error
+	})
+	if err != nil {
+		log.Printf("UpdateOrderStatus: order %d: %v", id, err)
+		respondError(c, err, "Could not update order")
+		return
+	}
+
+	realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderStatusChanged, order, nil))
+	if driverChanged != nil {
+		realtime.DefaultHub.Publish(realtime.NewDriverStatusEvent(driverChanged))
+	}
+	if order.Status == models.OrderCancelled && actor != models.RoleDriver {
+		pushToDriver(order.DriverID, notify.PushCancelled, order)
+	}
+	respondOrder(c, order)
+}
+
+// settleFare stores the final fare of a finished order. Orders taken before
+// any tariff existed stay without a price.
+func settleFare(tx *gorm.DB, order *models.Order, now time.Time) error {
+	trip := *order
+	if err := tx.Where("order_id = ?", order.ID).Order("position ASC").Find(&trip.Stops).Error; err != nil {
+		return err
+	}
+	fare, err := pricing.FinalFare(tx, &trip, now)
+	if errors.Is(err, pricing.ErrNoTariff) {
+		log.Printf("settleFare: order %d: %v", order.ID, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	order.FinalFare = fare
+	return nil
+}
+
+type RejectOrderInput struct {
+	Reason  models.RejectReason `json:"reason" binding:"required,oneof=vehicle_problem too_far passenger_unreachable personal other"`
+	Note    string              `json:"note"`
+	Version *uint               `json:"version"`
+}
+
+// RejectOrder lets a driver hand an assigned order back to the queue with a reason.
+// A pending auto-dispatch offer is closed and the order goes to the next candidate (Driver only)
+func RejectOrder(c *gin.Context) {
+	id, ok := parseOrderID(c)
+	if !ok {
+		return
+	}
+	driverID, _ := currentUser(c)
+
+	var input RejectOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	version, ok := expectedVersion(c, input.Version, false)
+	if !ok {
+		return
+	}
+
+	var order *models.Order
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if order, err = lockOrder(tx, id); err != nil {
+			return err
+		}
+		if order.DriverID == nil || *order.DriverID != driverID {
+			return newAPIError(http.StatusForbidden, "Not your order")
+		}
+		if err := checkVersion(order, version); err != nil {
+			return err
+		}
+		fromStatus := order.Status
+		if err := models.OrderFlow.Apply(order, models.RoleDriver, models.OrderNew); err != nil {
+			return err
+		}
+		if _, err := dispatch.ResolveOffer(tx, order.ID, driverID, models.OfferRejected); err != nil {
+			return err
+		}
+
+		order.DriverID = nil
+		if err := clearDriverProgress(tx, order); err != nil {
+			return err
+		}
+		order.UpdatedAt = time.Now()
+		if err := tx.Save(order).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", driverID).
+			UpdateColumn("rejected_orders", gorm.Expr("rejected_orders + 1")).Error; err != nil {
+			return err
+		}
+		event := models.NewOrderEvent(order, driverID, models.RoleDriver, fromStatus, &driverID, input.Note)
+		event.Reason = string(input.Reason)
+		return tx.Create(event).Error
+	})
+	if err != nil {
+		respondError(c, err, "Could not reject order")
+		return
 	}
 
-	order.UpdatedAt = time.Now()
-	if err := database.DB.Save(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update order"})
+	realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderStatusChanged, order, &driverID))
+	dispatch.DefaultEngine.Notify()
+	respondOrder(c, order)
+}
+
+// GetOrderEvents returns the audit timeline of an order, oldest first (Dispatcher only)
+func GetOrderEvents(c *gin.Context) {
+	id, ok := parseOrderID(c)
+	if !ok {
+		return
+	}
+
+	var order models.Order
+	if err := database.DB.First(&order, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, order)
+	var events []models.OrderEvent
+	if err := database.DB.Where("order_id = ?", order.ID).Order("created_at ASC, id ASC").Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch order events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// respondTransitionError maps state machine errors to HTTP responses.
+func respondTransitionError(c *gin.Context, err error) {
+	var transitionErr *models.TransitionError
+	var unknownErr *models.UnknownStatusError
+	switch {
+	case errors.As(err, &transitionErr):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":            "Invalid status transition",
+			"current_status":   transitionErr.From,
+			"requested_status": transitionErr.To,
+		})
+	case errors.As(err, &unknownErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update order"})
+	}
 }
@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"taxi-fleet-backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultOrderPageSize = 50
+	maxOrderPageSize     = 200
+)
+
+// orderCursor points at the last order of the previous page.
+type orderCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+func (cur orderCursor) encode() string {
+	raw := fmt.Sprintf("%d:%d", cur.CreatedAt.UnixNano(), cur.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeOrderCursor(s string) (*orderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid cursor")
+	}
+	nanos, err1 := strconv.ParseInt(parts[0], 10, 64)
+	id, err2 := strconv.ParseUint(parts[1], 10, 32)
+	if err1 != nil || err2 != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &orderCursor{CreatedAt: time.Unix(0, nanos), ID: uint(id)}, nil
+}
+
+// orderQuery holds the filters accepted by the order listing endpoints.
+type orderQuery struct {
+	Statuses    []models.OrderStatus
+	DriverID    *uint
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Search      string
+	Ascending   bool
+	Cursor      *orderCursor
+	Limit       int // 0 means no pagination
+}
+
+// parseOrderQuery reads filters from the query string:
+// status (comma separated or repeated), driver_id, from, to (RFC3339 or YYYY-MM-DD),
+// q, sort (asc|desc by created_at), cursor and limit. Without limit and cursor
+// the result is not paginated.
+func parseOrderQuery(c *gin.Context) (orderQuery, error) {
+	var q orderQuery
+
+	for _, value := range c.QueryArray("status") {
+		for _, s := range strings.Split(value, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			status := models.OrderStatus(s)
+			if !status.Valid() {
+				return q, fmt.Errorf("unknown status %q", s)
+			}
+			q.Statuses = append(q.Statuses, status)
+		}
+	}
+
+	if v := c.Query("driver_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return q, errors.New("invalid driver_id")
+		}
+		driverID := uint(id)
+		q.DriverID = &driverID
+	}
+
+	if v := c.Query("from"); v != "" {
+		t, err := parseQueryTime(v, false)
+		if err != nil {
+			return q, errors.New("invalid from date")
+		}
+		q.CreatedFrom = &t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := parseQueryTime(v, true)
+		if err != nil {
+			return q, errors.New("invalid to date")
+		}
+		q.CreatedTo = &t
+	}
+
+	q.Search = strings.TrimSpace(c.Query("q"))
+
+	switch c.DefaultQuery("sort", "desc") {
+	case "asc":
+		q.Ascending = true
+	case "desc":
+	default:
+		return q, errors.New("sort must be asc or desc")
+	}
+
+	if v := c.Query("cursor"); v != "" {
+		cur, err := decodeOrderCursor(v)
+		if err != nil {
+			return q, err
+		}
+		q.Cursor = cur
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return q, errors.New("invalid limit")
+		}
+		if limit > maxOrderPageSize {
+			limit = maxOrderPageSize
+		}
+		q.Limit = limit
+	}
+	if q.Cursor != nil && q.Limit == 0 {
+		q.Limit = defaultOrderPageSize
+	}
+
+	return q, nil
+}
+
+// parseQueryTime accepts RFC3339 timestamps or plain dates. A plain date used as
+// an upper bound covers the whole day.
+func parseQueryTime(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// filter applies every filter except pagination.
+func (q orderQuery) filter(db *gorm.DB) *gorm.DB {
+	if len(q.Statuses) > 0 {
+		db = db.Where("status IN ?", q.Statuses)
+	}
+	if q.DriverID != nil {
+		db = db.Where("driver_id = ?", *q.DriverID)
+	}
+	if q.CreatedFrom != nil {
+		db = db.Where("created_at >= ?", *q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		db = db.Where("created_at <= ?", *q.CreatedTo)
+	}
+	if q.Search != "" {
+		like := "%" + likeEscaper.Replace(q.Search) + "%"
+		db = db.Where("from_address ILIKE ? OR to_address ILIKE ? OR comment ILIKE ?", like, like, like)
+	}
+	return db
+}
+
+// likeEscaper makes % and _ in search text match literally (backslash is the default ILIKE escape).
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// page applies ordering, the cursor and the limit (one extra row to detect a next page).
+func (q orderQuery) page(db *gorm.DB) *gorm.DB {
+	if q.Ascending {
+		db = db.Order("created_at ASC, id ASC")
+		if q.Cursor != nil {
+			db = db.Where("(created_at, id) > (?, ?)", q.Cursor.CreatedAt, q.Cursor.ID)
+		}
+	} else {
+		db = db.Order("created_at DESC, id DESC")
+		if q.Cursor != nil {
+			db = db.Where("(created_at, id) < (?, ?)", q.Cursor.CreatedAt, q.Cursor.ID)
+		}
+	}
+	if q.Limit == 0 {
+		return db
+	}
+	return db.Limit(q.Limit + 1)
+}
+
+// orderPage is one page of orders with the total number of matches.
+type orderPage struct {
+	Items      []models.Order `json:"items"`
+	Total      int64          `json:"total"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// fetch runs the query against db (already scoped by role) and returns a page.
+func (q orderQuery) fetch(db *gorm.DB) (orderPage, error) {
+	var page orderPage
+	if err := q.filter(db.Session(&gorm.Session{}).Model(&models.Order{})).Count(&page.Total).Error; err != nil {
+		return page, err
+	}
+
+	var orders []models.Order
+	if err := q.page(q.filter(db.Session(&gorm.Session{}).Preload("Driver").Preload("Customer").Preload("Stops", orderedStops))).Find(&orders).Error; err != nil {
+		return page, err
+	}
+	if q.Limit > 0 && len(orders) > q.Limit {
+		orders = orders[:q.Limit]
+		last := orders[len(orders)-1]
+		page.NextCursor = orderCursor{CreatedAt: last.CreatedAt, ID: last.ID}.encode()
+	}
+	page.Items = orders
+	return page, nil
+}
+
+func orderedStops(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
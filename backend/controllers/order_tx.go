@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/dispatch"
+	"taxi-fleet-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// apiError carries an HTTP response out of a transaction closure.
+type apiError struct {
+	Status int
+	Body   gin.H
+}
+
+func (e *apiError) Error() string {
+	if msg, ok := e.Body["error"].(string); ok {
+		return msg
+	}
+	return http.StatusText(e.Status)
+}
+
+func newAPIError(status int, message string) *apiError {
+	return &apiError{Status: status, Body: gin.H{"error": message}}
+}
+
+// respondError writes err as an HTTP response: apiError as is, state machine
+// errors via respondTransitionError, anything else as 500 with fallback.
+func respondError(c *gin.Context, err error, fallback string) {
+	var apiErr *apiError
+	var transitionErr *models.TransitionError
+	var unknownErr *models.UnknownStatusError
+	switch {
+	case errors.As(err, &apiErr):
+		c.JSON(apiErr.Status, apiErr.Body)
+	case errors.As(err, &transitionErr), errors.As(err, &unknownErr):
+		respondTransitionError(c, err)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// lockOrder loads an order with SELECT ... FOR UPDATE.
+func lockOrder(tx *gorm.DB, id uint) (*models.Order, error) {
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, newAPIError(http.StatusNotFound, "Order not found")
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+// lockDriver loads a driver with SELECT ... FOR UPDATE.
+func lockDriver(tx *gorm.DB, id uint) (*models.User, error) {
+	var driver models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&driver, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, newAPIError(http.StatusNotFound, "Driver not found")
+		}
+		return nil, err
+	}
+	if driver.Role != models.RoleDriver {
+		return nil, newAPIError(http.StatusBadRequest, "User is not a driver")
+	}
+	return &driver, nil
+}
+
+// checkDriverAvailable fails with 409 if the driver is offline or, unless forced,
+// busy with an active order other than orderID.
+func checkDriverAvailable(tx *gorm.DB, driver *models.User, orderID uint, force bool) error {
+	if driver.DriverStatus == models.StatusOffline {
+		return newAPIError(http.StatusConflict, "Driver is offline")
+	}
+	if force {
+		return nil
+	}
+	other, err := dispatch.ActiveOrderOf(tx, driver.ID, orderID)
+	if err != nil {
+		return err
+	}
+	if other != 0 {
+		return &apiError{Status: http.StatusConflict, Body: gin.H{
+			"error":             "Driver already has an active order",
+			"conflict_order_id": other,
+		}}
+	}
+	return nil
+}
+
+// clearDriverProgress resets the timers and stop marks left by the previous
+// driver, so waiting time and stop arrivals start over for the next one.
+func clearDriverProgress(tx *gorm.DB, order *models.Order) error {
+	order.ClearDriverTimers()
+	for i := range order.Stops {
+		order.Stops[i].ArrivedAt = nil
+		order.Stops[i].DepartedAt = nil
+	}
+	return tx.Model(&models.OrderStop{}).
+		Where("order_id = ? AND (arrived_at IS NOT NULL OR departed_at IS NOT NULL)", order.ID).
+		Updates(map[string]interface{}{"arrived_at": nil, "departed_at": nil}).Error
+}
+
+// releaseDriver frees a busy driver who no longer has other active orders.
+// It returns the driver if the status changed.
+func releaseDriver(tx *gorm.DB, driverID, exceptOrderID uint) (*models.User, error) {
+	driver, err := lockDriver(tx, driverID)
+	if err != nil {
+		return nil, err
+	}
+	if driver.DriverStatus != models.StatusBusy {
+		return nil, nil
+	}
+	other, err := dispatch.ActiveOrderOf(tx, driverID, exceptOrderID)
+	if err != nil || other != 0 {
+		return nil, err
+	}
+	driver.DriverStatus = models.StatusFree
+	if err := tx.Save(driver).Error; err != nil {
+		return nil, err
+	}
+	return driver, nil
+}
+
+// orderETag is the entity tag of an order version.
+func orderETag(order *models.Order) string {
+	return fmt.Sprintf(`"%d-%d"`, order.ID, order.Version)
+}
+
+// respondOrder writes the order with its route and ETag.
+func respondOrder(c *gin.Context, order *models.Order) {
+	if order.Stops == nil {
+		database.DB.Where("order_id = ?", order.ID).Order("position ASC").Find(&order.Stops)
+	}
+	c.Header("ETag", orderETag(order))
+	c.JSON(http.StatusOK, order)
+}
+
+// expectedVersion reads the order version the client based its change on,
+// from If-Match (an ETag from respondOrder) or the body's version field.
+// With required set, a missing version is answered with 428.
+func expectedVersion(c *gin.Context, bodyVersion *uint, required bool) (*uint, bool) {
+	if ifMatch := strings.TrimSpace(c.GetHeader("If-Match")); ifMatch != "" {
+		tag := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+		if i := strings.LastIndex(tag, "-"); i >= 0 {
+			tag = tag[i+1:]
+		}
+		v, err := strconv.ParseUint(tag, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match header"})
+			return nil, false
+		}
+		version := uint(v)
+		return &version, true
+	}
+	if bodyVersion != nil {
+		return bodyVersion, true
+	}
+	if required {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "Order version required: send If-Match or version"})
+		return nil, false
+	}
+	return nil, true
+}
+
+// checkVersion fails with 409 and the current order if the client's copy is stale.
+func checkVersion(order *models.Order, expected *uint) error {
+	if expected == nil || *expected == order.Version {
+		return nil
+	}
+	current := *order
+	return &apiError{Status: http.StatusConflict, Body: gin.H{
+		"error": "Order was modified by someone else",
+		"order": &current,
+	}}
+}
@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PhoneFlagInput struct {
+	Phone    string              `json:"phone" binding:"required"`
+	Severity models.FlagSeverity `json:"severity" binding:"required,oneof=warning block"`
+	Reason   string              `json:"reason" binding:"required"`
+}
+
+type UpdatePhoneFlagInput struct {
+	Severity models.FlagSeverity `json:"severity" binding:"required,oneof=warning block"`
+	Reason   string              `json:"reason" binding:"required"`
+	Note     string              `json:"note"`
+}
+
+type RemovePhoneFlagInput struct {
+	Note string `json:"note"`
+}
+
+// GetPhoneFlags lists active flags, or all of them with ?all=true (Dispatcher only)
+func GetPhoneFlags(c *gin.Context) {
+	db := database.DB.Order("updated_at DESC")
+	if c.Query("all") != "true" {
+		db = db.Where("active = ?", true)
+	}
+	var flags []models.PhoneFlag
+	if err := db.Find(&flags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch flags"})
+		return
+	}
+	c.JSON(http.StatusOK, flags)
+}
+
+// FlagPhone flags a phone number. A removed flag for the same number is
+// reactivated (Dispatcher only)
+func FlagPhone(c *gin.Context) {
+	var input PhoneFlagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	phone := models.NormalizePhone(input.Phone)
+	if len(phone) < 5 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phone number"})
+		return
+	}
+
+	actorID, _ := currentUser(c)
+	var flag models.PhoneFlag
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("phone = ?", phone).First(&flag).Error
+		switch {
+		case err == nil && flag.Active:
+			return &apiError{Status: http.StatusConflict, Body: gin.H{"error": "Phone is already flagged", "flag_id": flag.ID}}
+		case err == nil:
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			flag = models.PhoneFlag{Phone: phone, CreatedByID: actorID}
+		default:
+			return err
+		}
+
+		flag.Severity = input.Severity
+		flag.Reason = input.Reason
+		flag.Active = true
+		flag.UpdatedByID = actorID
+		if err := savePhoneFlag(tx, &flag); err != nil {
+			return err
+		}
+		return tx.Create(models.NewPhoneFlagEvent(&flag, models.FlagCreated, actorID, "")).Error
+	})
+	if err != nil {
+		respondError(c, err, "Could not flag phone")
+		return
+	}
+	c.JSON(http.StatusOK, flag)
+}
+
+// UpdatePhoneFlag changes the severity or reason of an active flag (Dispatcher only)
+func UpdatePhoneFlag(c *gin.Context) {
+	var input UpdatePhoneFlagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	changePhoneFlag(c, models.FlagUpdated, input.Note, func(flag *models.PhoneFlag) {
+		flag.Severity = input.Severity
+		flag.Reason = input.Reason
+	})
+}
+
+// RemovePhoneFlag lifts a flag; it stays in the list with ?all=true (Dispatcher only)
+func RemovePhoneFlag(c *gin.Context) {
+	var input RemovePhoneFlagInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	changePhoneFlag(c, models.FlagRemoved, input.Note, func(flag *models.PhoneFlag) {
+		flag.Active = false
+	})
+}
+
+func changePhoneFlag(c *gin.Context, action models.FlagAction, note string, change func(*models.PhoneFlag)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flag ID format"})
+		return
+	}
+
+	actorID, _ := currentUser(c)
+	var flag models.PhoneFlag
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&flag, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return newAPIError(http.StatusNotFound, "Flag not found")
+			}
+			return err
+		}
+		if !flag.Active {
+			return newAPIError(http.StatusConflict, "Flag was removed")
+		}
+
+		change(&flag)
+		flag.UpdatedByID = actorID
+		if err := savePhoneFlag(tx, &flag); err != nil {
+			return err
+		}
+		return tx.Create(models.NewPhoneFlagEvent(&flag, action, actorID, note)).Error
+	})
+	if err != nil {
+		respondError(c, err, "Could not update flag")
+		return
+	}
+	c.JSON(http.StatusOK, flag)
+}
+
+// savePhoneFlag stores the flag and mirrors it onto the customer with that
+// phone, whose blacklist fields only reflect blocking flags.
+func savePhoneFlag(tx *gorm.DB, flag *models.PhoneFlag) error {
+	var customer models.Customer
+	err := tx.Where("phone = ?", flag.Phone).First(&customer).Error
+	switch {
+	case err == nil:
+		flag.CustomerID = &customer.ID
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+	if err := tx.Save(flag).Error; err != nil {
+		return err
+	}
+	if flag.CustomerID == nil {
+		return nil
+	}
+
+	blocked := flag.Active && flag.Severity == models.FlagBlock
+	reason := ""
+	if blocked {
+		reason = flag.Reason
+	}
+	return tx.Model(&customer).Updates(map[string]interface{}{"blacklisted": blocked, "blacklist_reason": reason}).Error
+}
+
+// markBlacklisted sets the customer's blacklist fields from the flag on their
+// phone, for customers created or renumbered after the phone was flagged.
+func markBlacklisted(db *gorm.DB, customer *models.Customer) error {
+	flag, err := activePhoneFlag(db, customer.Phone)
+	if err != nil {
+		return err
+	}
+	customer.Blacklisted = flag != nil && flag.Severity == models.FlagBlock
+	customer.BlacklistReason = ""
+	if customer.Blacklisted {
+		customer.BlacklistReason = flag.Reason
+	}
+	return nil
+}
+
+// GetPhoneFlagEvents returns the audit trail of a flag, oldest first (Dispatcher only)
+func GetPhoneFlagEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flag ID format"})
+		return
+	}
+
+	var events []models.PhoneFlagEvent
+	if err := database.DB.Where("flag_id = ?", id).Order("created_at ASC, id ASC").Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch flag events"})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// activePhoneFlag returns the active flag on phone, or nil.
+func activePhoneFlag(db *gorm.DB, phone string) (*models.PhoneFlag, error) {
+	var flag models.PhoneFlag
+	err := db.Where("phone = ? AND active = ?", phone, true).First(&flag).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
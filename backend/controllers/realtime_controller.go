@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"taxi-fleet-backend/realtime"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const socketPingInterval = 30 * time.Second
+
+// OrderUpdatesSocket streams order and driver events over a WebSocket.
+// Drivers receive events for their own orders, dispatchers for the whole fleet.
+func OrderUpdatesSocket(c *gin.Context) {
+	userID, role := currentUser(c)
+
+	server := websocket.Server{
+		// Clients are authenticated by JWT, so the Origin check is not needed.
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			sub := realtime.DefaultHub.Subscribe(userID, role)
+			defer realtime.DefaultHub.Unsubscribe(sub)
+
+			// Incoming messages are ignored; reading only detects the client going away.
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				var msg string
+				for websocket.Message.Receive(ws, &msg) == nil {
+				}
+			}()
+
+			ping := time.NewTicker(socketPingInterval)
+			defer ping.Stop()
+
+			for {
+				select {
+				case <-closed:
+					return
+				case event, ok := <-sub.C:
+					if !ok {
+						return
+					}
+					if err := websocket.JSON.Send(ws, event); err != nil {
+						log.Printf("OrderUpdatesSocket: send to user %d failed: %v", userID, err)
+						return
+					}
+				case <-ping.C:
+					if err := websocket.JSON.Send(ws, gin.H{"type": "ping", "at": time.Now()}); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// OrderUpdatesStream is the Server-Sent Events variant of OrderUpdatesSocket for
+// clients behind proxies that break WebSockets. Each message carries the event
+// ID, and a reconnecting client sending Last-Event-ID (or ?last_event_id=)
+// first receives the buffered events it missed.
+func OrderUpdatesStream(c *gin.Context) {
+	userID, role := currentUser(c)
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var lastID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+		lastID = id
+	}
+
+	sub, backlog := realtime.DefaultHub.SubscribeSince(userID, role, lastID)
+	defer realtime.DefaultHub.Unsubscribe(sub)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	for _, event := range backlog {
+		c.Render(-1, sseEvent(event))
+	}
+	c.Writer.Flush()
+
+	ping := time.NewTicker(socketPingInterval)
+	defer ping.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			c.Render(-1, sseEvent(event))
+			return true
+		case <-ping.C:
+			c.Render(-1, sse.Event{Event: "ping", Data: time.Now().Unix()})
+			return true
+		}
+	})
+}
+
+func sseEvent(event realtime.Event) sse.Event {
+	return sse.Event{
+		Id:    strconv.FormatUint(event.ID, 10),
+		Event: string(event.Type),
+		Data:  event,
+	}
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/models"
+	"taxi-fleet-backend/realtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MarkStopArrived records that the driver reached a stop of their order (Driver only)
+func MarkStopArrived(c *gin.Context) {
+	markStop(c, true)
+}
+
+// MarkStopDeparted records that the driver left a stop of their order (Driver only)
+func MarkStopDeparted(c *gin.Context) {
+	markStop(c, false)
+}
+
+// MarkArrived records that the driver is waiting at the pickup; the waiting
+// timer runs until the order goes in progress (Driver only)
+func MarkArrived(c *gin.Context) {
+	id, ok := parseOrderID(c)
+	if !ok {
+		return
+	}
+	driverID, _ := currentUser(c)
+
+	var order *models.Order
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if order, err = lockOrder(tx, id); err != nil {
+			return err
+		}
+		if order.DriverID == nil || *order.DriverID != driverID {
+			return newAPIError(http.StatusForbidden, "Not your order")
+		}
+		if order.Status != models.OrderAccepted {
+			return &apiError{Status: http.StatusConflict, Body: gin.H{
+				"error":          "Arrival can only be marked on accepted orders",
+				"current_status": order.Status,
+			}}
+		}
+		if order.ArrivedAt != nil {
+			return newAPIError(http.StatusConflict, "Arrival already marked")
+		}
+
+		now := time.Now()
+		order.ArrivedAt = &now
+		order.UpdatedAt = now
+		if err := tx.Save(order).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.OrderStop{}).
+			Where("order_id = ? AND type = ? AND arrived_at IS NULL", order.ID, models.StopPickup).
+			Update("arrived_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(models.NewOrderEvent(order, driverID, models.RoleDriver, order.Status, order.DriverID, "arrived at pickup")).Error
+	})
+	if err != nil {
+		respondError(c, err, "Could not mark arrival")
+		return
+	}
+
+	realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderDriverArrived, order, nil))
+	respondOrder(c, order)
+}
+
+func markStop(c *gin.Context, arrived bool) {
+	id, ok := parseOrderID(c)
+	if !ok {
+		return
+	}
+	stopID, err := strconv.ParseUint(c.Param("stopId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stop ID format"})
+		return
+	}
+	driverID, _ := currentUser(c)
+
+	var order *models.Order
+	driverArrived := false
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if order, err = lockOrder(tx, id); err != nil {
+			return err
+		}
+		if order.DriverID == nil || *order.DriverID != driverID {
+			return newAPIError(http.StatusForbidden, "Not your order")
+		}
+		if order.Status != models.OrderAccepted && order.Status != models.OrderInProgress {
+			return &apiError{Status: http.StatusConflict, Body: gin.H{
+				"error":          "Stops can only be marked on accepted or in-progress orders",
+				"current_status": order.Status,
+			}}
+		}
+
+		var stop models.OrderStop
+		if err := tx.Where("id = ? AND order_id = ?", stopID, order.ID).First(&stop).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return newAPIError(http.StatusNotFound, "Stop not found")
+			}
+			return err
+		}
+
+		now := time.Now()
+		var note string
+		if arrived {
+			if stop.ArrivedAt != nil {
+				return newAPIError(http.StatusConflict, "Stop already reached")
+			}
+			if stop.Type == models.StopPickup && order.Status != models.OrderAccepted {
+				// Same rule as MarkArrived: the pickup is reached before the trip starts.
+				return &apiError{Status: http.StatusConflict, Body: gin.H{
+					"error":          "Arrival can only be marked on accepted orders",
+					"current_status": order.Status,
+				}}
+			}
+			stop.ArrivedAt = &now
+			note = fmt.Sprintf("arrived at stop %d (%s)", stop.Position, stop.Address)
+			if stop.Type == models.StopPickup && order.ArrivedAt == nil {
+				order.ArrivedAt = &now
+				driverArrived = true
+			}
+		} else {
+			if stop.ArrivedAt == nil {
+				return newAPIError(http.StatusConflict, "Stop not reached yet")
+			}
+			if stop.DepartedAt != nil {
+				return newAPIError(http.StatusConflict, "Stop already left")
+			}
+			stop.DepartedAt = &now
+			note = fmt.Sprintf("departed from stop %d (%s)", stop.Position, stop.Address)
+		}
+		if err := tx.Save(&stop).Error; err != nil {
+			return err
+		}
+
+		order.UpdatedAt = now
+		if err := tx.Save(order).Error; err != nil {
+			return err
+		}
+		return tx.Create(models.NewOrderEvent(order, driverID, models.RoleDriver, order.Status, order.DriverID, note)).Error
+	})
+	if err != nil {
+		respondError(c, err, "Could not update stop")
+		return
+	}
+
+	database.DB.Where("order_id = ?", order.ID).Order("position ASC").Find(&order.Stops)
+	realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderStopUpdated, order, nil))
+	if driverArrived {
+		realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderDriverArrived, order, nil))
+	}
+	respondOrder(c, order)
+}
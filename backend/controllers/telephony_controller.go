@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/models"
+	"taxi-fleet-backend/realtime"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TelephonyCallInput struct {
+	Event     string `json:"event" binding:"required,oneof=ringing answered hangup"`
+	CallID    string `json:"call_id" binding:"required"`
+	Caller    string `json:"caller"`                                            // Caller ID, may be empty for hidden numbers
+	Extension string `json:"extension" binding:"required_unless=Event ringing"` // Extension that picked up
+}
+
+// callDraft prefills the new order form for an incoming call.
+type callDraft struct {
+	Order              CreateOrderInput   `json:"order"`
+	FavouriteAddresses []favouriteAddress `json:"favourite_addresses"`
+	LastOrders         []models.Order     `json:"last_orders"`
+}
+
+// TelephonyCall receives call events from the PBX (signed, see
+// middleware.TelephonySignature). When a dispatcher answers, the caller is
+// looked up and a draft order is pushed to that dispatcher's event stream.
+func TelephonyCall(c *gin.Context) {
+	var input TelephonyCallInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	caller := models.NormalizePhone(input.Caller)
+
+	if input.Event == "ringing" {
+		// Lets the PBX show the caller's name on the ringing phones.
+		var customer models.Customer
+		name := ""
+		if caller != "" && database.DB.Where("phone = ?", caller).First(&customer).Error == nil {
+			name = customer.Name
+		}
+		c.JSON(http.StatusOK, gin.H{"call_id": input.CallID, "customer_name": name})
+		return
+	}
+
+	var dispatcher models.User
+	err := database.DB.Where("role = ? AND extension = ?", models.RoleDispatcher, strings.TrimSpace(input.Extension)).
+		First(&dispatcher).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("TelephonyCall: no dispatcher with extension %q (call %s)", input.Extension, input.CallID)
+		c.JSON(http.StatusOK, gin.H{"call_id": input.CallID, "delivered": false})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not find dispatcher"})
+		return
+	}
+
+	call := &realtime.CallInfo{CallID: input.CallID, Caller: caller, Extension: dispatcher.Extension}
+	if input.Event == "hangup" {
+		realtime.DefaultHub.Publish(realtime.NewCallEvent(realtime.CallEnded, dispatcher.ID, call))
+		c.JSON(http.StatusOK, gin.H{"call_id": input.CallID, "delivered": true})
+		return
+	}
+
+	if caller != "" {
+		if call.Flag, err = activePhoneFlag(database.DB, caller); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not look up caller"})
+			return
+		}
+		draft := callDraft{
+			Order:              CreateOrderInput{CustomerPhone: caller},
+			FavouriteAddresses: []favouriteAddress{},
+			LastOrders:         []models.Order{},
+		}
+		lookup, err := lookupCustomer(caller)
+		switch {
+		case err == nil:
+			call.Customer = &lookup.Customer
+			draft.Order.CustomerID = &lookup.Customer.ID
+			draft.Order.CustomerName = lookup.Customer.Name
+			draft.FavouriteAddresses = lookup.FavouriteAddresses
+			draft.LastOrders = lookup.LastOrders
+			// Callers mostly ride from where they rode from last time.
+			if len(lookup.LastOrders) > 0 {
+				last := lookup.LastOrders[0]
+				draft.Order.FromAddress = last.FromAddress
+				draft.Order.PickupLat, draft.Order.PickupLon = last.PickupLat, last.PickupLon
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not look up caller"})
+			return
+		}
+		call.Draft = draft
+	}
+
+	realtime.DefaultHub.Publish(realtime.NewCallEvent(realtime.CallIncoming, dispatcher.ID, call))
+	c.JSON(http.StatusOK, gin.H{"call_id": input.CallID, "delivered": true, "dispatcher_id": dispatcher.ID})
+}
+
+type SetExtensionInput struct {
+	Extension string `json:"extension" binding:"max=32"` // Empty to stop receiving calls
+}
+
+// SetTelephonyExtension binds the calling dispatcher to a PBX extension; the
+// extension is taken away from whoever had it before (Dispatcher only)
+func SetTelephonyExtension(c *gin.Context) {
+	var input SetExtensionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	extension := strings.TrimSpace(input.Extension)
+	userID, _ := currentUser(c)
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if extension != "" {
+			if err := tx.Model(&models.User{}).Where("extension = ? AND id <> ?", extension, userID).
+				Update("extension", "").Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update("extension", extension).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not set extension"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Extension updated", "extension": extension})
+}
@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/models"
+	"taxi-fleet-backend/scheduler"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CreateOrderTemplateInput struct {
+	Name        string   `json:"name" binding:"required"`
+	FromAddress string   `json:"from_address" binding:"required"`
+	ToAddress   string   `json:"to_address" binding:"required"`
+	PickupLat   *float64 `json:"pickup_lat" binding:"required_with=PickupLon,omitempty,min=-90,max=90"`
+	PickupLon   *float64 `json:"pickup_lon" binding:"required_with=PickupLat,omitempty,min=-180,max=180"`
+	Comment     string   `json:"comment"`
+	DriverID    *uint    `json:"driver_id"`
+	Weekdays    string   `json:"weekdays" binding:"required"`    // e.g. "MO,TU,WE,TH,FR"
+	PickupTime  string   `json:"pickup_time" binding:"required"` // HH:MM
+	Timezone    string   `json:"timezone"`
+	StartsOn    string   `json:"starts_on" binding:"required"` // YYYY-MM-DD
+	EndsOn      *string  `json:"ends_on"`
+	Exceptions  []string `json:"exceptions"`
+}
+
+// CreateOrderTemplate creates a recurring booking and generates its first orders (Dispatcher only)
+func CreateOrderTemplate(c *gin.Context) {
+	var input CreateOrderTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actorID, _ := currentUser(c)
+	tpl := models.OrderTemplate{
+		Name:        input.Name,
+		FromAddress: input.FromAddress,
+		ToAddress:   input.ToAddress,
+		PickupLat:   input.PickupLat,
+		PickupLon:   input.PickupLon,
+		Comment:     input.Comment,
+		DriverID:    input.DriverID,
+		Weekdays:    input.Weekdays,
+		PickupTime:  input.PickupTime,
+		Timezone:    input.Timezone,
+		StartsOn:    input.StartsOn,
+		EndsOn:      input.EndsOn,
+		Exceptions:  input.Exceptions,
+		CreatedByID: actorID,
+	}
+	if err := tpl.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if tpl.DriverID != nil {
+			if _, err := lockDriver(tx, *tpl.DriverID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&tpl).Error
+	})
+	if err != nil {
+		respondError(c, err, "Could not create template")
+		return
+	}
+
+	if _, err := scheduler.GenerateFromTemplate(tpl.ID, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Template created, but orders could not be generated"})
+		return
+	}
+	database.DB.First(&tpl, tpl.ID)
+	c.JSON(http.StatusOK, tpl)
+}
+
+// GetOrderTemplates lists recurring bookings (Dispatcher only)
+func GetOrderTemplates(c *gin.Context) {
+	var templates []models.OrderTemplate
+	if err := database.DB.Order("id ASC").Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch templates"})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// PauseOrderTemplate stops generating orders; already generated ones are kept (Dispatcher only)
+func PauseOrderTemplate(c *gin.Context) {
+	setTemplatePaused(c, true)
+}
+
+// ResumeOrderTemplate restarts order generation from now on (Dispatcher only)
+func ResumeOrderTemplate(c *gin.Context) {
+	setTemplatePaused(c, false)
+}
+
+func setTemplatePaused(c *gin.Context, paused bool) {
+	tpl, ok := findTemplate(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Model(tpl).Update("paused", paused).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update template"})
+		return
+	}
+	if !paused {
+		// Occurrences missed while paused are not generated retroactively.
+		if _, err := scheduler.GenerateFromTemplate(tpl.ID, time.Now()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate orders"})
+			return
+		}
+	}
+	database.DB.First(tpl, tpl.ID)
+	c.JSON(http.StatusOK, tpl)
+}
+
+type templateOccurrence struct {
+	ScheduledAt time.Time           `json:"scheduled_at"`
+	OrderID     *uint               `json:"order_id"`
+	Status      *models.OrderStatus `json:"status"`
+}
+
+// GetTemplateOccurrences lists upcoming occurrences for ?days= (default 14),
+// with the generated order if there is one (Dispatcher only)
+func GetTemplateOccurrences(c *gin.Context) {
+	tpl, ok := findTemplate(c)
+	if !ok {
+		return
+	}
+
+	days := 14
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 90 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 90"})
+			return
+		}
+		days = n
+	}
+
+	now := time.Now()
+	until := now.AddDate(0, 0, days)
+	occurrences, err := tpl.Occurrences(now, until)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var orders []models.Order
+	if err := database.DB.Where("template_id = ? AND scheduled_at > ? AND scheduled_at <= ?", tpl.ID, now, until).Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch orders"})
+		return
+	}
+	byTime := make(map[int64]models.Order, len(orders))
+	for _, o := range orders {
+		byTime[o.ScheduledAt.Unix()] = o
+	}
+
+	result := make([]templateOccurrence, len(occurrences))
+	for i, at := range occurrences {
+		result[i] = templateOccurrence{ScheduledAt: at}
+		if o, ok := byTime[at.Unix()]; ok {
+			id, status := o.ID, o.Status
+			result[i].OrderID = &id
+			result[i].Status = &status
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func findTemplate(c *gin.Context) (*models.OrderTemplate, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID format"})
+		return nil, false
+	}
+	var tpl models.OrderTemplate
+	if err := database.DB.First(&tpl, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return nil, false
+	}
+	return &tpl, true
+}
@@ -2,6 +2,8 @@ package controllers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 	"taxi-fleet-backend/database"
 	"taxi-fleet-backend/models"
 
@@ -12,6 +14,9 @@ type CreateDriverInput struct {
 	Name     string `json:"name" binding:"required"`
 	Phone    string `json:"phone" binding:"required"`
 	Password string `json:"password" binding:"required,min=6"`
+	CarMake  string `json:"car_make"`
+	CarColor string `json:"car_color"`
+	CarPlate string `json:"car_plate"`
 }
 
 // CreateDriver creates a new driver (Dispatcher only)
@@ -27,6 +32,9 @@ func CreateDriver(c *gin.Context) {
 		Phone:        input.Phone,
 		Role:         models.RoleDriver,
 		DriverStatus: models.StatusOffline,
+		CarMake:      input.CarMake,
+		CarColor:     input.CarColor,
+		CarPlate:     input.CarPlate,
 	}
 
 	if err := user.SetPassword(input.Password); err != nil {
@@ -45,6 +53,61 @@ func CreateDriver(c *gin.Context) {
 	})
 }
 
+type UpdateDriverInput struct {
+	Name     *string `json:"name" binding:"omitempty,min=1"`
+	Phone    *string `json:"phone" binding:"omitempty,min=1"`
+	CarMake  *string `json:"car_make"`
+	CarColor *string `json:"car_color"`
+	CarPlate *string `json:"car_plate"`
+}
+
+// UpdateDriver changes a driver's name, phone or car; omitted fields are kept (Dispatcher only)
+func UpdateDriver(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid driver ID format"})
+		return
+	}
+
+	var input UpdateDriverInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("role = ?", models.RoleDriver).First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver not found"})
+		return
+	}
+
+	if input.Name != nil {
+		user.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Phone != nil {
+		user.Phone = strings.TrimSpace(*input.Phone)
+	}
+	if input.CarMake != nil {
+		user.CarMake = strings.TrimSpace(*input.CarMake)
+	}
+	if input.CarColor != nil {
+		user.CarColor = strings.TrimSpace(*input.CarColor)
+	}
+	if input.CarPlate != nil {
+		user.CarPlate = strings.TrimSpace(*input.CarPlate)
+	}
+
+	if err := database.DB.Save(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not update driver, phone might be taken"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Driver updated successfully",
+		"user":    user,
+	})
+}
+
 type ChangePasswordInput struct {
 	OldPassword string `json:"old_password" binding:"required"`
 	NewPassword string `json:"new_password" binding:"required,min=6"`
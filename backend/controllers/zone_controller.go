@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/geo"
+	"taxi-fleet-backend/models"
+	"taxi-fleet-backend/pricing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ZoneInput struct {
+	Name             string          `json:"name" binding:"required"`
+	Geometry         json.RawMessage `json:"geometry" binding:"required"` // GeoJSON Polygon, MultiPolygon or Feature
+	TariffID         *uint           `json:"tariff_id"`
+	PickupSurcharge  float64         `json:"pickup_surcharge" binding:"min=0"`
+	DropoffSurcharge float64         `json:"dropoff_surcharge" binding:"min=0"`
+	Priority         int             `json:"priority"`
+	Active           *bool           `json:"active"` // Defaults to true
+}
+
+// apply validates the input and copies it onto z.
+func (in ZoneInput) apply(z *models.Zone) error {
+	if _, err := geo.ParseShape(in.Geometry); err != nil {
+		return err
+	}
+	if in.TariffID != nil {
+		var count int64
+		if err := database.DB.Model(&models.Tariff{}).Where("id = ?", *in.TariffID).Count(&count).Error; err != nil || count == 0 {
+			return fmt.Errorf("tariff %d not found", *in.TariffID)
+		}
+	}
+	z.Name = in.Name
+	z.Geometry = in.Geometry
+	z.TariffID = in.TariffID
+	z.PickupSurcharge = in.PickupSurcharge
+	z.DropoffSurcharge = in.DropoffSurcharge
+	z.Priority = in.Priority
+	z.Active = in.Active == nil || *in.Active
+	return nil
+}
+
+// GetZones lists all tariff zones (Dispatcher only)
+func GetZones(c *gin.Context) {
+	var zones []models.Zone
+	if err := database.DB.Order("priority DESC, id ASC").Find(&zones).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch zones"})
+		return
+	}
+	c.JSON(http.StatusOK, zones)
+}
+
+// CreateZone (Dispatcher only)
+func CreateZone(c *gin.Context) {
+	var input ZoneInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var zone models.Zone
+	if err := input.apply(&zone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Create(&zone).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create zone"})
+		return
+	}
+	c.JSON(http.StatusOK, zone)
+}
+
+// UpdateZone replaces a zone's polygon and prices (Dispatcher only)
+func UpdateZone(c *gin.Context) {
+	zone, ok := findZone(c)
+	if !ok {
+		return
+	}
+
+	var input ZoneInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.apply(zone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Save(zone).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update zone"})
+		return
+	}
+	c.JSON(http.StatusOK, zone)
+}
+
+// DeleteZone removes a zone and its fixed prices. Orders keep their zone IDs (Dispatcher only)
+func DeleteZone(c *gin.Context) {
+	zone, ok := findZone(c)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("from_zone_id = ? OR to_zone_id = ?", zone.ID, zone.ID).Delete(&models.ZoneFare{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(zone).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete zone"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Zone deleted"})
+}
+
+type zoneFeatureCollection struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Geometry   json.RawMessage `json:"geometry"`
+		Properties struct {
+			Name             string  `json:"name"`
+			TariffID         *uint   `json:"tariff_id"`
+			PickupSurcharge  float64 `json:"pickup_surcharge"`
+			DropoffSurcharge float64 `json:"dropoff_surcharge"`
+			Priority         int     `json:"priority"`
+			Active           *bool   `json:"active"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+// ImportZones creates or updates zones by name from a GeoJSON FeatureCollection.
+// Zone settings are read from each feature's properties (Dispatcher only)
+func ImportZones(c *gin.Context) {
+	var input zoneFeatureCollection
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Type != "FeatureCollection" || len(input.Features) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expected a FeatureCollection with at least one feature"})
+		return
+	}
+
+	zones := make([]models.Zone, len(input.Features))
+	for i, f := range input.Features {
+		if f.Properties.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("feature %d: properties.name is required", i)})
+			return
+		}
+		if f.Properties.PickupSurcharge < 0 || f.Properties.DropoffSurcharge < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("feature %d: surcharges must not be negative", i)})
+			return
+		}
+		in := ZoneInput{
+			Name:             f.Properties.Name,
+			Geometry:         f.Geometry,
+			TariffID:         f.Properties.TariffID,
+			PickupSurcharge:  f.Properties.PickupSurcharge,
+			DropoffSurcharge: f.Properties.DropoffSurcharge,
+			Priority:         f.Properties.Priority,
+			Active:           f.Properties.Active,
+		}
+		if err := in.apply(&zones[i]); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("feature %d (%s): %v", i, in.Name, err)})
+			return
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range zones {
+			var existing models.Zone
+			err := tx.Where("name = ?", zones[i].Name).First(&existing).Error
+			switch {
+			case err == nil:
+				zones[i].ID = existing.ID
+				zones[i].CreatedAt = existing.CreatedAt
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				return err
+			}
+			if err := tx.Save(&zones[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not import zones"})
+		return
+	}
+	c.JSON(http.StatusOK, zones)
+}
+
+// LocateZone returns the active zone containing ?lat=&lon=
+func LocateZone(c *gin.Context) {
+	lat, err1 := strconv.ParseFloat(c.Query("lat"), 64)
+	lon, err2 := strconv.ParseFloat(c.Query("lon"), 64)
+	if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Valid lat and lon are required"})
+		return
+	}
+
+	zones, err := pricing.ActiveZones(database.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch zones"})
+		return
+	}
+	zone := pricing.ZoneAt(zones, geo.Point{Lat: lat, Lon: lon})
+	if zone == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Point is outside every zone"})
+		return
+	}
+	c.JSON(http.StatusOK, zone)
+}
+
+// GetZoneFares returns the inter-zone fixed price matrix (Dispatcher only)
+func GetZoneFares(c *gin.Context) {
+	var fares []models.ZoneFare
+	if err := database.DB.Order("from_zone_id ASC, to_zone_id ASC").Find(&fares).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch zone fares"})
+		return
+	}
+	c.JSON(http.StatusOK, fares)
+}
+
+type ZoneFareInput struct {
+	FromZoneID uint    `json:"from_zone_id" binding:"required"`
+	ToZoneID   uint    `json:"to_zone_id" binding:"required"`
+	Price      float64 `json:"price" binding:"min=0"`
+}
+
+type UpdateZoneFaresInput struct {
+	Fares []ZoneFareInput `json:"fares" binding:"dive"`
+}
+
+// UpdateZoneFares replaces the whole inter-zone fixed price matrix (Dispatcher only)
+func UpdateZoneFares(c *gin.Context) {
+	var input UpdateZoneFaresInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	type route struct{ from, to uint }
+	seen := make(map[route]bool, len(input.Fares))
+	zoneIDs := make(map[uint]bool)
+	fares := make([]models.ZoneFare, len(input.Fares))
+	for i, f := range input.Fares {
+		r := route{f.FromZoneID, f.ToZoneID}
+		if seen[r] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("duplicate price for zones %d -> %d", f.FromZoneID, f.ToZoneID)})
+			return
+		}
+		seen[r] = true
+		zoneIDs[f.FromZoneID], zoneIDs[f.ToZoneID] = true, true
+		fares[i] = models.ZoneFare{FromZoneID: f.FromZoneID, ToZoneID: f.ToZoneID, Price: f.Price}
+	}
+
+	ids := make([]uint, 0, len(zoneIDs))
+	for id := range zoneIDs {
+		ids = append(ids, id)
+	}
+	var count int64
+	if len(ids) > 0 {
+		if err := database.DB.Model(&models.Zone{}).Where("id IN ?", ids).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check zones"})
+			return
+		}
+	}
+	if int(count) != len(ids) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown zone in matrix"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.ZoneFare{}).Error; err != nil {
+			return err
+		}
+		if len(fares) == 0 {
+			return nil
+		}
+		return tx.Create(&fares).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save zone fares"})
+		return
+	}
+	c.JSON(http.StatusOK, fares)
+}
+
+func findZone(c *gin.Context) (*models.Zone, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID format"})
+		return nil, false
+	}
+	var zone models.Zone
+	if err := database.DB.First(&zone, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Zone not found"})
+		return nil, false
+	}
+	return &zone, true
+}
@@ -0,0 +1,61 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"taxi-fleet-backend/models"
+)
+
+// DriverUnavailableError tells why a driver cannot take an order right now.
+type DriverUnavailableError struct {
+	DriverID    uint
+	Reason      string
+	ActiveOrder uint // The other order, if the driver is busy
+}
+
+func (e *DriverUnavailableError) Error() string {
+	return fmt.Sprintf("driver %d %s", e.DriverID, e.Reason)
+}
+
+// ActiveOrderOf returns the ID of another active order of the driver, or 0.
+func ActiveOrderOf(tx *gorm.DB, driverID, exceptOrderID uint) (uint, error) {
+	var ids []uint
+	err := tx.Model(&models.Order{}).
+		Where("driver_id = ? AND id <> ? AND status IN ?", driverID, exceptOrderID, models.ActiveStatuses).
+		Limit(1).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// LockAvailableDriver loads the driver with SELECT ... FOR UPDATE and checks
+// that they can take orderID now: online and without another active order.
+// Otherwise it returns a *DriverUnavailableError.
+func LockAvailableDriver(tx *gorm.DB, driverID, orderID uint) (*models.User, error) {
+	var driver models.User
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&driver, driverID).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, &DriverUnavailableError{DriverID: driverID, Reason: "does not exist"}
+	case err != nil:
+		return nil, err
+	case driver.Role != models.RoleDriver:
+		return nil, &DriverUnavailableError{DriverID: driverID, Reason: "is not a driver"}
+	case driver.DriverStatus == models.StatusOffline:
+		return nil, &DriverUnavailableError{DriverID: driverID, Reason: "is offline"}
+	}
+	other, err := ActiveOrderOf(tx, driverID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if other != 0 {
+		return nil, &DriverUnavailableError{DriverID: driverID, Reason: fmt.Sprintf("is busy with order %d", other), ActiveOrder: other}
+	}
+	return &driver, nil
+}
@@ -0,0 +1,87 @@
+package dispatch
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"taxi-fleet-backend/geo"
+	"taxi-fleet-backend/models"
+)
+
+// Candidate is a free driver who could take an order.
+type Candidate struct {
+	DriverID       uint       `json:"driver_id"`
+	Name           string     `json:"name"`
+	Phone          string     `json:"phone"`
+	DistanceMeters *float64   `json:"distance_meters"`
+	LastSeenAt     *time.Time `json:"last_seen_at"`
+	LastSeenAgeSec *int64     `json:"last_seen_age_seconds"`
+	Stale          bool       `json:"stale"`
+}
+
+// FindCandidates returns free drivers without active orders, nearest to the
+// order's pickup point first. Drivers with no known location come last.
+// A limit of 0 returns every candidate.
+func FindCandidates(db *gorm.DB, order *models.Order, limit int) ([]Candidate, error) {
+	busy := db.Model(&models.Order{}).
+		Select("driver_id").
+		Where("driver_id IS NOT NULL AND status IN ?", models.ActiveStatuses)
+
+	var drivers []models.User
+	if err := db.Where("role = ? AND driver_status = ? AND id NOT IN (?)", models.RoleDriver, models.StatusFree, busy).
+		Find(&drivers).Error; err != nil {
+		return nil, err
+	}
+	if len(drivers) == 0 {
+		return []Candidate{}, nil
+	}
+
+	ids := make([]uint, len(drivers))
+	for i, d := range drivers {
+		ids[i] = d.ID
+	}
+	var locations []models.DriverLocation
+	if err := db.Where("driver_id IN ?", ids).Find(&locations).Error; err != nil {
+		return nil, err
+	}
+	byDriver := make(map[uint]models.DriverLocation, len(locations))
+	for _, l := range locations {
+		byDriver[l.DriverID] = l
+	}
+
+	now := time.Now()
+	candidates := make([]Candidate, 0, len(drivers))
+	for _, d := range drivers {
+		cand := Candidate{DriverID: d.ID, Name: d.Name, Phone: d.Phone}
+		if l, ok := byDriver[d.ID]; ok {
+			recordedAt := l.RecordedAt
+			age := int64(now.Sub(recordedAt).Seconds())
+			cand.LastSeenAt = &recordedAt
+			cand.LastSeenAgeSec = &age
+			cand.Stale = now.Sub(recordedAt) > models.StaleLocationAfter
+			if order.HasPickupPoint() {
+				dist := geo.Distance(geo.Point{Lat: *order.PickupLat, Lon: *order.PickupLon}, geo.Point{Lat: l.Lat, Lon: l.Lon})
+				cand.DistanceMeters = &dist
+			}
+		}
+		candidates = append(candidates, cand)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i].DistanceMeters, candidates[j].DistanceMeters
+		switch {
+		case a == nil:
+			return false
+		case b == nil:
+			return true
+		}
+		return *a < *b
+	})
+
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
@@ -0,0 +1,368 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/models"
+	"taxi-fleet-backend/notify"
+	"taxi-fleet-backend/realtime"
+)
+
+const (
+	defaultOfferTimeout = 30 * time.Second
+	defaultRetryAfter   = 2 * time.Minute
+	tickInterval        = 2 * time.Second
+	// queueBatch bounds how many queued orders are offered per tick.
+	queueBatch = 50
+)
+
+// Config controls the auto-dispatch engine.
+type Config struct {
+	Enabled      bool
+	Strategy     string
+	OfferTimeout time.Duration
+	// RetryAfter is how long a driver who rejected or missed an offer is
+	// skipped before the order may be offered to them again.
+	RetryAfter time.Duration
+}
+
+// ConfigFromEnv reads AUTO_DISPATCH_ENABLED, AUTO_DISPATCH_STRATEGY,
+// AUTO_DISPATCH_OFFER_TIMEOUT and AUTO_DISPATCH_RETRY_AFTER (seconds).
+func ConfigFromEnv() Config {
+	cfg := Config{Strategy: StrategyNearest, OfferTimeout: defaultOfferTimeout, RetryAfter: defaultRetryAfter}
+	if v, err := strconv.ParseBool(os.Getenv("AUTO_DISPATCH_ENABLED")); err == nil {
+		cfg.Enabled = v
+	}
+	if v := os.Getenv("AUTO_DISPATCH_STRATEGY"); v != "" {
+		if _, err := StrategyByName(v); err != nil {
+			log.Printf("dispatch: %v, using %s", err, cfg.Strategy)
+		} else {
+			cfg.Strategy = v
+		}
+	}
+	if v, err := strconv.Atoi(os.Getenv("AUTO_DISPATCH_OFFER_TIMEOUT")); err == nil && v > 0 {
+		cfg.OfferTimeout = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("AUTO_DISPATCH_RETRY_AFTER")); err == nil && v > 0 {
+		cfg.RetryAfter = time.Duration(v) * time.Second
+	}
+	return cfg
+}
+
+// Engine offers queued orders to free drivers one at a time. An offer assigns
+// the order to the driver; if the driver neither accepts nor rejects it before
+// it expires, the order returns to the queue and goes to the next candidate.
+// When no candidate is left, because no driver is free or every one declined,
+// dispatchers are alerted; drivers who declined get the order again after RetryAfter.
+type Engine struct {
+	mu   sync.RWMutex
+	cfg  Config
+	wake chan struct{}
+
+	// exhausted holds the orders dispatchers were alerted about in the current round.
+	exhausted map[uint]bool
+}
+
+func NewEngine(cfg Config) *Engine {
+	return &Engine{cfg: cfg, wake: make(chan struct{}, 1), exhausted: make(map[uint]bool)}
+}
+
+// DefaultEngine is configured and started from main.
+var DefaultEngine = NewEngine(Config{Strategy: StrategyNearest, OfferTimeout: defaultOfferTimeout, RetryAfter: defaultRetryAfter})
+
+func (e *Engine) Config() Config {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.cfg
+}
+
+// Configure replaces the engine settings at runtime.
+func (e *Engine) Configure(cfg Config) error {
+	if _, err := StrategyByName(cfg.Strategy); err != nil {
+		return err
+	}
+	if cfg.OfferTimeout <= 0 {
+		return fmt.Errorf("offer timeout must be positive")
+	}
+	if cfg.RetryAfter <= 0 {
+		return fmt.Errorf("retry delay must be positive")
+	}
+	e.mu.Lock()
+	e.cfg = cfg
+	e.mu.Unlock()
+	e.Notify()
+	return nil
+}
+
+// Notify makes the worker run without waiting for the next tick,
+// e.g. after an order was created or an offer rejected.
+func (e *Engine) Notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes offers until ctx is cancelled.
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-e.wake:
+		}
+		e.tick()
+	}
+}
+
+func (e *Engine) tick() {
+	// Pending offers expire even when auto-dispatch has been switched off.
+	if err := e.expireOffers(); err != nil {
+		log.Printf("dispatch: expiring offers: %v", err)
+	}
+
+	cfg := e.Config()
+	if !cfg.Enabled {
+		return
+	}
+
+	var ids []uint
+	err := database.DB.Model(&models.Order{}).
+		Where("status = ? AND driver_id IS NULL", models.OrderNew).
+		Order("created_at ASC").
+		Limit(queueBatch).
+		Pluck("id", &ids).Error
+	if err != nil {
+		log.Printf("dispatch: loading queue: %v", err)
+		return
+	}
+	e.forgetExhausted(ids)
+	for _, id := range ids {
+		if err := e.offer(id, cfg); err != nil {
+			log.Printf("dispatch: offering order %d: %v", id, err)
+		}
+	}
+}
+
+// offer proposes a queued order to the best candidate that has not seen it yet.
+func (e *Engine) offer(orderID uint, cfg Config) error {
+	strategy, err := StrategyByName(cfg.Strategy)
+	if err != nil {
+		return err
+	}
+
+	var order models.Order
+	offered, exhausted := false, false
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
+			return err
+		}
+		if order.Status != models.OrderNew || order.DriverID != nil {
+			return nil
+		}
+
+		candidates, err := FindCandidates(tx, &order, 0)
+		if err != nil {
+			return err
+		}
+		var tried []uint
+		err = tx.Model(&models.OrderOffer{}).
+			Where("order_id = ? AND created_at > ?", order.ID, time.Now().Add(-cfg.RetryAfter)).
+			Pluck("driver_id", &tried).Error
+		if err != nil {
+			return err
+		}
+		candidates = excludeDrivers(candidates, tried)
+
+		// The candidate list is read without locks, so the pick is locked and
+		// checked again; a dispatcher may have given the driver another order.
+		var pick *Candidate
+		for pick == nil {
+			if len(candidates) == 0 {
+				exhausted = true
+				return nil
+			}
+			if pick, err = strategy.Pick(tx, candidates); err != nil || pick == nil {
+				return err
+			}
+			_, err := LockAvailableDriver(tx, pick.DriverID, order.ID)
+			var unavailable *DriverUnavailableError
+			switch {
+			case errors.As(err, &unavailable):
+				candidates = excludeDrivers(candidates, []uint{pick.DriverID})
+				pick = nil
+			case err != nil:
+				return err
+			}
+		}
+
+		fromStatus := order.Status
+		driverID := pick.DriverID
+		order.DriverID = &driverID
+		if err := models.OrderFlow.Apply(&order, models.RoleSystem, models.OrderAssigned); err != nil {
+			return err
+		}
+		if err := tx.Save(&order).Error; err != nil {
+			return err
+		}
+		offer := models.OrderOffer{
+			OrderID:   order.ID,
+			DriverID:  driverID,
+			Strategy:  strategy.Name(),
+			Status:    models.OfferPending,
+			ExpiresAt: time.Now().Add(cfg.OfferTimeout),
+		}
+		if err := tx.Create(&offer).Error; err != nil {
+			return err
+		}
+		note := fmt.Sprintf("auto-dispatch offer (%s), expires in %s", strategy.Name(), cfg.OfferTimeout)
+		if err := tx.Create(models.NewOrderEvent(&order, 0, models.RoleSystem, fromStatus, nil, note)).Error; err != nil {
+			return err
+		}
+		offered = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if exhausted {
+		e.alertExhausted(&order)
+	}
+	if offered {
+		e.mu.Lock()
+		delete(e.exhausted, order.ID)
+		e.mu.Unlock()
+		realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderAssigned, &order, nil))
+		notify.DefaultPusher.NotifyDriver(*order.DriverID, notify.PushAssigned, &order)
+	}
+	return nil
+}
+
+// alertExhausted tells dispatchers, once per round, that no driver is left to
+// offer the order to and it may need to be assigned by hand.
+func (e *Engine) alertExhausted(order *models.Order) {
+	e.mu.Lock()
+	alerted := e.exhausted[order.ID]
+	e.exhausted[order.ID] = true
+	e.mu.Unlock()
+	if alerted {
+		return
+	}
+	log.Printf("dispatch: no driver left to offer order %d to", order.ID)
+	realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderDispatchExhausted, order, nil))
+}
+
+// forgetExhausted drops alerts for orders that left the queue.
+func (e *Engine) forgetExhausted(queued []uint) {
+	inQueue := make(map[uint]bool, len(queued))
+	for _, id := range queued {
+		inQueue[id] = true
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for id := range e.exhausted {
+		if !inQueue[id] {
+			delete(e.exhausted, id)
+		}
+	}
+}
+
+// expireOffers returns orders whose offer timed out to the queue.
+func (e *Engine) expireOffers() error {
+	var offers []models.OrderOffer
+	if err := database.DB.Where("status = ? AND expires_at < ?", models.OfferPending, time.Now()).Find(&offers).Error; err != nil {
+		return err
+	}
+	for _, offer := range offers {
+		var order models.Order
+		released := false
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, offer.OrderID).Error; err != nil {
+				return err
+			}
+			n, err := resolveOffer(tx, offer.OrderID, offer.DriverID, models.OfferExpired)
+			if err != nil || n == 0 {
+				// Accepted or rejected in the meantime.
+				return err
+			}
+			if order.Status != models.OrderAssigned || order.DriverID == nil || *order.DriverID != offer.DriverID {
+				return nil
+			}
+
+			driverBefore := order.DriverID
+			order.DriverID = nil
+			if err := models.OrderFlow.Apply(&order, models.RoleSystem, models.OrderNew); err != nil {
+				return err
+			}
+			if err := tx.Save(&order).Error; err != nil {
+				return err
+			}
+			released = true
+			return tx.Create(models.NewOrderEvent(&order, 0, models.RoleSystem, models.OrderAssigned, driverBefore, "auto-dispatch offer expired")).Error
+		})
+		if err != nil {
+			log.Printf("dispatch: expiring offer %d: %v", offer.ID, err)
+			continue
+		}
+		if released {
+			realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderStatusChanged, &order, &offer.DriverID))
+			notify.DefaultPusher.NotifyDriver(offer.DriverID, notify.PushExpired, &order)
+		}
+	}
+	return nil
+}
+
+func excludeDrivers(candidates []Candidate, ids []uint) []Candidate {
+	if len(ids) == 0 {
+		return candidates
+	}
+	skip := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		skip[id] = true
+	}
+	kept := candidates[:0]
+	for _, c := range candidates {
+		if !skip[c.DriverID] {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+func resolveOffer(tx *gorm.DB, orderID, driverID uint, status models.OfferStatus) (int64, error) {
+	now := time.Now()
+	res := tx.Model(&models.OrderOffer{}).
+		Where("order_id = ? AND driver_id = ? AND status = ?", orderID, driverID, models.OfferPending).
+		Updates(map[string]interface{}{"status": status, "responded_at": now})
+	return res.RowsAffected, res.Error
+}
+
+// ResolveOffer closes the driver's pending offer for the order with the given
+// status and reports whether there was one. Orders assigned by hand have none.
+func ResolveOffer(tx *gorm.DB, orderID, driverID uint, status models.OfferStatus) (bool, error) {
+	n, err := resolveOffer(tx, orderID, driverID, status)
+	return n > 0, err
+}
+
+// WithdrawOffers closes any pending offer for the order, e.g. when a dispatcher
+// assigns or cancels it by hand.
+func WithdrawOffers(tx *gorm.DB, orderID uint) error {
+	return tx.Model(&models.OrderOffer{}).
+		Where("order_id = ? AND status = ?", orderID, models.OfferPending).
+		Updates(map[string]interface{}{"status": models.OfferWithdrawn, "responded_at": time.Now()}).Error
+}
@@ -0,0 +1,116 @@
+package dispatch
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"taxi-fleet-backend/models"
+)
+
+// Strategy chooses which candidate receives an auto-dispatch offer.
+type Strategy interface {
+	Name() string
+	// Pick returns one of candidates, or nil if none should get the order.
+	Pick(db *gorm.DB, candidates []Candidate) (*Candidate, error)
+}
+
+const (
+	StrategyNearest     = "nearest"
+	StrategyLongestIdle = "longest_idle"
+	StrategyRoundRobin  = "round_robin"
+)
+
+// StrategyByName returns the strategy registered under name.
+func StrategyByName(name string) (Strategy, error) {
+	switch name {
+	case StrategyNearest:
+		return nearest{}, nil
+	case StrategyLongestIdle:
+		return longestIdle{}, nil
+	case StrategyRoundRobin:
+		return roundRobin{}, nil
+	}
+	return nil, fmt.Errorf("unknown dispatch strategy %q", name)
+}
+
+// nearest relies on FindCandidates already ordering by distance to the pickup.
+type nearest struct{}
+
+func (nearest) Name() string { return StrategyNearest }
+
+func (nearest) Pick(_ *gorm.DB, candidates []Candidate) (*Candidate, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	return &candidates[0], nil
+}
+
+// longestIdle prefers the driver whose last order was closed the longest time ago.
+type longestIdle struct{}
+
+func (longestIdle) Name() string { return StrategyLongestIdle }
+
+func (longestIdle) Pick(db *gorm.DB, candidates []Candidate) (*Candidate, error) {
+	var rows []driverTimestamp
+	err := db.Model(&models.Order{}).
+		Select("driver_id, MAX(updated_at) AS last").
+		Where("driver_id IN ? AND status IN ?", candidateIDs(candidates), []models.OrderStatus{models.OrderDone, models.OrderCancelled}).
+		Group("driver_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return pickOldest(candidates, rows), nil
+}
+
+// roundRobin prefers the driver who least recently received an offer.
+type roundRobin struct{}
+
+func (roundRobin) Name() string { return StrategyRoundRobin }
+
+func (roundRobin) Pick(db *gorm.DB, candidates []Candidate) (*Candidate, error) {
+	var rows []driverTimestamp
+	err := db.Model(&models.OrderOffer{}).
+		Select("driver_id, MAX(created_at) AS last").
+		Where("driver_id IN ?", candidateIDs(candidates)).
+		Group("driver_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return pickOldest(candidates, rows), nil
+}
+
+func candidateIDs(candidates []Candidate) []uint {
+	ids := make([]uint, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.DriverID
+	}
+	return ids
+}
+
+type driverTimestamp struct {
+	DriverID uint
+	Last     time.Time
+}
+
+// pickOldest returns the candidate with the earliest timestamp; drivers without
+// one win. Ties keep the candidates' order (nearest first).
+func pickOldest(candidates []Candidate, rows []driverTimestamp) *Candidate {
+	if len(candidates) == 0 {
+		return nil
+	}
+	last := make(map[uint]time.Time, len(rows))
+	for _, r := range rows {
+		last[r.DriverID] = r.Last
+	}
+	best := 0
+	for i := 1; i < len(candidates); i++ {
+		if last[candidates[i].DriverID].Before(last[candidates[best].DriverID]) {
+			best = i
+		}
+	}
+	return &candidates[best]
+}
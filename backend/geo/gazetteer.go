@@ -0,0 +1,272 @@
+package geo
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// reverseRadiusMeters bounds how far Reverse looks for a gazetteer entry.
+const reverseRadiusMeters = 500
+
+// GazetteerEntry is a street, landmark or address of the local gazetteer.
+type GazetteerEntry struct {
+	Name    string
+	Aliases []string
+	Kind    string
+	Point   Point
+
+	tokens [][]string // normalized tokens of Name and each alias
+}
+
+// Gazetteer is an offline Geocoder over a fixed list of places.
+type Gazetteer struct {
+	entries []GazetteerEntry
+}
+
+// NewGazetteer indexes entries for searching.
+func NewGazetteer(entries []GazetteerEntry) *Gazetteer {
+	g := &Gazetteer{entries: entries}
+	for i := range g.entries {
+		e := &g.entries[i]
+		e.tokens = [][]string{tokenize(e.Name)}
+		for _, a := range e.Aliases {
+			e.tokens = append(e.tokens, tokenize(a))
+		}
+	}
+	return g
+}
+
+// LoadGazetteer reads a .csv or .geojson gazetteer file.
+func LoadGazetteer(path string) (*Gazetteer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []GazetteerEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		entries, err = ReadGazetteerCSV(f)
+	case ".geojson", ".json":
+		entries, err = ReadGazetteerGeoJSON(f)
+	default:
+		return nil, fmt.Errorf("unsupported gazetteer format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewGazetteer(entries), nil
+}
+
+// ReadGazetteerCSV reads rows of name,kind,lat,lon[,aliases] with a header row.
+// Aliases are separated by "|".
+func ReadGazetteerCSV(r io.Reader) ([]GazetteerEntry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	if _, err := cr.Read(); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	var entries []GazetteerEntry
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) < 4 {
+			return nil, fmt.Errorf("line %d: expected name,kind,lat,lon[,aliases]", line)
+		}
+		lat, err1 := strconv.ParseFloat(rec[2], 64)
+		lon, err2 := strconv.ParseFloat(rec[3], 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("line %d: invalid coordinates", line)
+		}
+		e := GazetteerEntry{Name: rec[0], Kind: rec[1], Point: Point{Lat: lat, Lon: lon}}
+		if len(rec) > 4 && rec[4] != "" {
+			e.Aliases = strings.Split(rec[4], "|")
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// ReadGazetteerGeoJSON reads a FeatureCollection of Point features with "name",
+// optional "kind" and optional "aliases" (array of strings) properties.
+func ReadGazetteerGeoJSON(r io.Reader) ([]GazetteerEntry, error) {
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Type        string    `json:"type"`
+				Coordinates []float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties struct {
+				Name    string   `json:"name"`
+				Kind    string   `json:"kind"`
+				Aliases []string `json:"aliases"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return nil, err
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, errors.New("expected a GeoJSON FeatureCollection")
+	}
+
+	var entries []GazetteerEntry
+	for i, f := range fc.Features {
+		if f.Geometry.Type != "Point" || len(f.Geometry.Coordinates) < 2 {
+			return nil, fmt.Errorf("feature %d: only Point geometries are supported", i)
+		}
+		if f.Properties.Name == "" {
+			return nil, fmt.Errorf("feature %d: missing name", i)
+		}
+		entries = append(entries, GazetteerEntry{
+			Name:    f.Properties.Name,
+			Kind:    f.Properties.Kind,
+			Aliases: f.Properties.Aliases,
+			// GeoJSON positions are [lon, lat].
+			Point: Point{Lat: f.Geometry.Coordinates[1], Lon: f.Geometry.Coordinates[0]},
+		})
+	}
+	return entries, nil
+}
+
+func (g *Gazetteer) Len() int { return len(g.entries) }
+
+func (g *Gazetteer) Search(_ context.Context, query string, limit int) ([]Place, error) {
+	return g.match(tokenize(query), false, limit), nil
+}
+
+func (g *Gazetteer) Autocomplete(_ context.Context, prefix string, limit int) ([]Place, error) {
+	return g.match(tokenize(prefix), true, limit), nil
+}
+
+func (g *Gazetteer) Reverse(_ context.Context, p Point) (*Place, error) {
+	var best *GazetteerEntry
+	bestDist := float64(reverseRadiusMeters)
+	for i := range g.entries {
+		if d := Distance(p, g.entries[i].Point); d <= bestDist {
+			best, bestDist = &g.entries[i], d
+		}
+	}
+	if best == nil {
+		return nil, ErrNotFound
+	}
+	place := best.place(0)
+	return &place, nil
+}
+
+func (e *GazetteerEntry) place(score float64) Place {
+	return Place{Name: e.Name, Kind: e.Kind, Point: e.Point, Score: score}
+}
+
+// match scores every entry against the query tokens. Each query token must
+// match some token of the name (or an alias) exactly, as a prefix, or within a
+// small edit distance to tolerate typos. With lastIsPrefix the final token is
+// what the user is still typing and only needs to be a prefix.
+func (g *Gazetteer) match(query []string, lastIsPrefix bool, limit int) []Place {
+	if len(query) == 0 {
+		return []Place{}
+	}
+	var places []Place
+	for i := range g.entries {
+		e := &g.entries[i]
+		best := 0.0
+		for _, tokens := range e.tokens {
+			if s := scoreTokens(query, tokens, lastIsPrefix); s > best {
+				best = s
+			}
+		}
+		if best > 0 {
+			places = append(places, e.place(best))
+		}
+	}
+	sort.SliceStable(places, func(i, j int) bool { return places[i].Score > places[j].Score })
+	if limit > 0 && len(places) > limit {
+		places = places[:limit]
+	}
+	if places == nil {
+		places = []Place{}
+	}
+	return places
+}
+
+func scoreTokens(query, tokens []string, lastIsPrefix bool) float64 {
+	total := 0.0
+	for qi, q := range query {
+		best := 0.0
+		for _, t := range tokens {
+			var s float64
+			switch {
+			case q == t:
+				s = 1
+			case strings.HasPrefix(t, q) && (lastIsPrefix && qi == len(query)-1 || len([]rune(q)) >= 3):
+				s = 0.8
+			case len([]rune(q)) >= 4 && levenshtein(q, t) <= maxTypos(q):
+				s = 0.6
+			}
+			if s > best {
+				best = s
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	// Prefer names without many extra words.
+	return total / float64(max(len(query), len(tokens)))
+}
+
+func maxTypos(s string) int {
+	if len([]rune(s)) >= 8 {
+		return 2
+	}
+	return 1
+}
+
+// tokenize lowercases, folds ё to е and splits on anything but letters and digits.
+func tokenize(s string) []string {
+	s = strings.ToLower(strings.ReplaceAll(strings.ReplaceAll(s, "ё", "е"), "Ё", "е"))
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
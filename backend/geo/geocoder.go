@@ -0,0 +1,71 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Place is a geocoding result.
+type Place struct {
+	Name    string  `json:"name"`
+	Address string  `json:"address,omitempty"`
+	Kind    string  `json:"kind,omitempty"` // street, landmark, house, ...
+	Point   Point   `json:"point"`
+	Score   float64 `json:"score,omitempty"` // Higher is better; only comparable within one response
+}
+
+// ErrNotFound is returned by Reverse when nothing is near the point.
+var ErrNotFound = errors.New("geo: no place found")
+
+// Geocoder turns addresses into coordinates and back.
+type Geocoder interface {
+	// Search returns places matching a full address or place name.
+	Search(ctx context.Context, query string, limit int) ([]Place, error)
+	// Reverse returns the place closest to p.
+	Reverse(ctx context.Context, p Point) (*Place, error)
+	// Autocomplete returns places whose name starts with what the user typed so far.
+	Autocomplete(ctx context.Context, prefix string, limit int) ([]Place, error)
+}
+
+// DefaultGeocoder is used by the API; nil when geocoding is not configured.
+var DefaultGeocoder Geocoder
+
+// FromEnv builds the geocoder selected by GEOCODER:
+//   - "gazetteer": offline lookup in GAZETTEER_PATH (.csv or .geojson)
+//   - "nominatim": HTTP lookup against NOMINATIM_URL
+//
+// An empty GEOCODER disables geocoding.
+func FromEnv() (Geocoder, error) {
+	switch kind := os.Getenv("GEOCODER"); kind {
+	case "":
+		return nil, nil
+	case "gazetteer":
+		path := os.Getenv("GAZETTEER_PATH")
+		if path == "" {
+			return nil, errors.New("GAZETTEER_PATH is not set")
+		}
+		g, err := LoadGazetteer(path)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("geo: loaded %d gazetteer entries from %s", g.Len(), path)
+		return g, nil
+	case "nominatim":
+		baseURL := os.Getenv("NOMINATIM_URL")
+		if baseURL == "" {
+			return nil, errors.New("NOMINATIM_URL is not set")
+		}
+		return &Nominatim{
+			BaseURL:      baseURL,
+			CountryCodes: os.Getenv("NOMINATIM_COUNTRY_CODES"),
+			UserAgent:    os.Getenv("NOMINATIM_USER_AGENT"),
+			Timeout:      5 * time.Second,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown GEOCODER %q", kind)
+	}
+}
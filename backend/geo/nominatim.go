@@ -0,0 +1,138 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultUserAgent = "taxi-fleet-backend"
+
+// Nominatim is a Geocoder backed by a Nominatim-compatible HTTP server.
+type Nominatim struct {
+	BaseURL      string // e.g. https://nominatim.openstreetmap.org
+	CountryCodes string // optional, e.g. "kz"
+	UserAgent    string // required by the public server's usage policy
+	Timeout      time.Duration
+	Client       *http.Client
+}
+
+type nominatimPlace struct {
+	Name        string  `json:"name"`
+	DisplayName string  `json:"display_name"`
+	Type        string  `json:"type"`
+	Category    string  `json:"category"`
+	Lat         string  `json:"lat"`
+	Lon         string  `json:"lon"`
+	Importance  float64 `json:"importance"`
+	Error       string  `json:"error"`
+}
+
+func (p nominatimPlace) place() (Place, error) {
+	lat, err1 := strconv.ParseFloat(p.Lat, 64)
+	lon, err2 := strconv.ParseFloat(p.Lon, 64)
+	if err1 != nil || err2 != nil {
+		return Place{}, fmt.Errorf("nominatim: invalid coordinates %q,%q", p.Lat, p.Lon)
+	}
+	name := p.Name
+	if name == "" {
+		name = strings.SplitN(p.DisplayName, ",", 2)[0]
+	}
+	return Place{
+		Name:    name,
+		Address: p.DisplayName,
+		Kind:    p.Type,
+		Point:   Point{Lat: lat, Lon: lon},
+		Score:   p.Importance,
+	}, nil
+}
+
+func (n *Nominatim) Search(ctx context.Context, query string, limit int) ([]Place, error) {
+	params := url.Values{"q": {query}}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	if n.CountryCodes != "" {
+		params.Set("countrycodes", n.CountryCodes)
+	}
+
+	var raw []nominatimPlace
+	if err := n.get(ctx, "/search", params, &raw); err != nil {
+		return nil, err
+	}
+	places := make([]Place, 0, len(raw))
+	for _, r := range raw {
+		p, err := r.place()
+		if err != nil {
+			return nil, err
+		}
+		places = append(places, p)
+	}
+	return places, nil
+}
+
+// Autocomplete falls back to Search: Nominatim has no prefix search.
+func (n *Nominatim) Autocomplete(ctx context.Context, prefix string, limit int) ([]Place, error) {
+	return n.Search(ctx, prefix, limit)
+}
+
+func (n *Nominatim) Reverse(ctx context.Context, p Point) (*Place, error) {
+	params := url.Values{
+		"lat": {strconv.FormatFloat(p.Lat, 'f', 7, 64)},
+		"lon": {strconv.FormatFloat(p.Lon, 'f', 7, 64)},
+	}
+	var raw nominatimPlace
+	if err := n.get(ctx, "/reverse", params, &raw); err != nil {
+		return nil, err
+	}
+	if raw.Error != "" {
+		return nil, ErrNotFound
+	}
+	place, err := raw.place()
+	if err != nil {
+		return nil, err
+	}
+	return &place, nil
+}
+
+func (n *Nominatim) get(ctx context.Context, path string, params url.Values, out interface{}) error {
+	if n.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.Timeout)
+		defer cancel()
+	}
+	params.Set("format", "jsonv2")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(n.BaseURL, "/")+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	ua := n.UserAgent
+	if ua == "" {
+		ua = defaultUserAgent
+	}
+	req.Header.Set("User-Agent", ua)
+	req.Header.Set("Accept-Language", "ru,kk,en")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("nominatim: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nominatim: unexpected status %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("nominatim: decoding response: %w", err)
+	}
+	return nil
+}
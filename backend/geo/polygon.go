@@ -0,0 +1,130 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Polygon is an outer ring followed by optional holes. Rings need not repeat
+// their first point at the end.
+type Polygon [][]Point
+
+// Shape is a set of polygons, the union of which is the covered area.
+type Shape []Polygon
+
+// Contains reports whether p lies inside the outer ring and outside every hole.
+func (pg Polygon) Contains(p Point) bool {
+	if len(pg) == 0 || !ringContains(pg[0], p) {
+		return false
+	}
+	for _, hole := range pg[1:] {
+		if ringContains(hole, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains reports whether any polygon of the shape contains p.
+func (s Shape) Contains(p Point) bool {
+	for _, pg := range s {
+		if pg.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ringContains is the even-odd ray casting test, with longitude as x.
+func ringContains(ring []Point, p Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Geometry    json.RawMessage `json:"geometry"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// ParseShape reads a GeoJSON Polygon or MultiPolygon geometry, or a Feature
+// holding one.
+func ParseShape(raw []byte) (Shape, error) {
+	var obj geoJSONObject
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+
+	switch obj.Type {
+	case "Feature":
+		if len(obj.Geometry) == 0 || string(obj.Geometry) == "null" {
+			return nil, errors.New("feature has no geometry")
+		}
+		return ParseShape(obj.Geometry)
+	case "Polygon":
+		var coords [][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("invalid polygon coordinates: %w", err)
+		}
+		pg, err := toPolygon(coords)
+		if err != nil {
+			return nil, err
+		}
+		return Shape{pg}, nil
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("invalid multipolygon coordinates: %w", err)
+		}
+		if len(coords) == 0 {
+			return nil, errors.New("multipolygon has no polygons")
+		}
+		shape := make(Shape, 0, len(coords))
+		for _, c := range coords {
+			pg, err := toPolygon(c)
+			if err != nil {
+				return nil, err
+			}
+			shape = append(shape, pg)
+		}
+		return shape, nil
+	}
+	return nil, fmt.Errorf("unsupported GeoJSON type %q, expected Polygon, MultiPolygon or Feature", obj.Type)
+}
+
+// toPolygon converts GeoJSON rings of [lon, lat] positions.
+func toPolygon(rings [][][]float64) (Polygon, error) {
+	if len(rings) == 0 {
+		return nil, errors.New("polygon has no rings")
+	}
+	pg := make(Polygon, 0, len(rings))
+	for _, ring := range rings {
+		points := make([]Point, 0, len(ring))
+		for _, pos := range ring {
+			if len(pos) < 2 {
+				return nil, errors.New("position needs longitude and latitude")
+			}
+			p := Point{Lat: pos[1], Lon: pos[0]}
+			if p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+				return nil, fmt.Errorf("position [%g, %g] is out of range", pos[0], pos[1])
+			}
+			points = append(points, p)
+		}
+		if len(points) > 1 && points[0] == points[len(points)-1] {
+			points = points[:len(points)-1]
+		}
+		if len(points) < 3 {
+			return nil, errors.New("ring needs at least three distinct positions")
+		}
+		pg = append(pg, points)
+	}
+	return pg, nil
+}
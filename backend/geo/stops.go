@@ -0,0 +1,38 @@
+package geo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"taxi-fleet-backend/models"
+)
+
+// geocodeTimeout bounds the address lookups done while preparing an order's stops.
+const geocodeTimeout = 3 * time.Second
+
+// LocateStops fills in missing stop coordinates from their addresses. Lookup
+// failures are logged and leave the stop without coordinates.
+func LocateStops(ctx context.Context, stops []models.OrderStop) {
+	if DefaultGeocoder == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, geocodeTimeout)
+	defer cancel()
+
+	for i := range stops {
+		if stops[i].Lat != nil || stops[i].Address == "" {
+			continue
+		}
+		places, err := DefaultGeocoder.Search(ctx, stops[i].Address, 1)
+		if err != nil {
+			log.Printf("geo: geocoding %q: %v", stops[i].Address, err)
+			continue
+		}
+		if len(places) == 0 {
+			continue
+		}
+		lat, lon := places[0].Point.Lat, places[0].Point.Lon
+		stops[i].Lat, stops[i].Lon = &lat, &lon
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -11,8 +13,14 @@ import (
 
 	"taxi-fleet-backend/controllers"
 	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/dispatch"
+	"taxi-fleet-backend/geo"
 	"taxi-fleet-backend/middleware"
 	"taxi-fleet-backend/models"
+	"taxi-fleet-backend/notify"
+	"taxi-fleet-backend/pricing"
+	"taxi-fleet-backend/realtime"
+	"taxi-fleet-backend/scheduler"
 )
 
 func main() {
@@ -25,7 +33,24 @@ func main() {
 	database.Connect()
 
 	// Auto Migrate
-	err := database.DB.AutoMigrate(&models.User{}, &models.Order{})
+	err := database.DB.AutoMigrate(
+		&models.User{},
+		&models.Customer{},
+		&models.PhoneFlag{},
+		&models.PhoneFlagEvent{},
+		&models.Order{},
+		&models.OrderStop{},
+		&models.OrderEvent{},
+		&models.DriverLocation{},
+		&models.DriverLocationPoint{},
+		&models.OrderOffer{},
+		&models.IdempotencyKey{},
+		&models.Device{},
+		&models.OrderTemplate{},
+		&models.Tariff{},
+		&models.Zone{},
+		&models.ZoneFare{},
+	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
@@ -33,6 +58,48 @@ func main() {
 	// Seed initial admin if empty
 	database.Seed()
 
+	// Address lookup (optional, see geo.FromEnv)
+	geocoder, err := geo.FromEnv()
+	if err != nil {
+		log.Fatal("Invalid geocoder config:", err)
+	}
+	geo.DefaultGeocoder = geocoder
+
+	// Airports that carry the tariffs' airport fees
+	airports, err := pricing.AirportsFromEnv()
+	if err != nil {
+		log.Fatal("Invalid PRICING_AIRPORTS:", err)
+	}
+	pricing.Airports = airports
+
+	// Auto-dispatch worker (disabled unless AUTO_DISPATCH_ENABLED or switched on by a dispatcher)
+	if err := dispatch.DefaultEngine.Configure(dispatch.ConfigFromEnv()); err != nil {
+		log.Fatal("Invalid auto-dispatch config:", err)
+	}
+	go dispatch.DefaultEngine.Run(context.Background())
+	go middleware.PurgeIdempotencyKeys(context.Background(), time.Hour)
+
+	// Passenger SMS (disabled unless SMS_SENDER is set)
+	sms, err := notify.SMSFromEnv()
+	if err != nil {
+		log.Fatal("Invalid SMS config:", err)
+	}
+	if sms != nil {
+		go notify.New(sms).Run(context.Background(), realtime.DefaultHub)
+	}
+
+	// Driver push notifications (disabled unless PUSH_SENDER is set)
+	push, err := notify.PushFromEnv()
+	if err != nil {
+		log.Fatal("Invalid push config:", err)
+	}
+	if push != nil {
+		notify.DefaultPusher = notify.NewPusher(push)
+	}
+
+	// Releases pre-booked orders into the live queue
+	go scheduler.New(scheduler.ConfigFromEnv()).Run(context.Background())
+
 	r := gin.New()
 	r.Use(gin.Recovery())
 	// Обработка OPTIONS ДО роутинга (httprouter не знает про OPTIONS)
@@ -40,7 +107,7 @@ func main() {
 		if c.Request.Method == "OPTIONS" {
 			c.Header("Access-Control-Allow-Origin", "*")
 			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
+			c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, Last-Event-ID, If-Match, Idempotency-Key")
 			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
@@ -50,10 +117,11 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowAllOrigins:  true,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization", "Last-Event-ID", "If-Match", "Idempotency-Key"},
+		ExposeHeaders:    []string{"X-Total-Count", "X-Next-Cursor", "ETag", "Idempotent-Replayed"},
 		AllowCredentials: false,
 	}))
-	r.Use(gin.Logger())
+	r.Use(middleware.Logger())
 
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -82,17 +150,109 @@ func main() {
 		// Driver Routes
 		api.GET("/drivers", middleware.RoleMiddleware("dispatcher"), controllers.GetDrivers)
 		api.POST("/drivers", middleware.RoleMiddleware("dispatcher"), controllers.CreateDriver)
+		api.PUT("/drivers/:id", middleware.RoleMiddleware("dispatcher"), controllers.UpdateDriver)
 		api.PUT("/drivers/status", middleware.RoleMiddleware("driver"), controllers.UpdateDriverStatus)
+		api.POST("/drivers/location", middleware.RoleMiddleware("driver"), controllers.ReportLocation)
+		api.GET("/drivers/locations", middleware.RoleMiddleware("dispatcher"), controllers.GetDriverLocations)
+
+		// Push notification devices
+		api.POST("/devices", controllers.RegisterDevice)
+		api.DELETE("/devices", controllers.UnregisterDevice)
+
+		// Geocoding
+		api.GET("/geo/search", controllers.GeoSearch)
+		api.GET("/geo/reverse", controllers.GeoReverse)
+
+		// Telephony
+		api.PUT("/telephony/extension", middleware.RoleMiddleware("dispatcher"), controllers.SetTelephonyExtension)
+
+		// Customer directory
+		customers := api.Group("/customers")
+		customers.Use(middleware.RoleMiddleware("dispatcher"))
+		{
+			customers.GET("/lookup", controllers.LookupCustomer)
+			customers.PUT("/:id", controllers.UpdateCustomer)
+			customers.POST("", controllers.CreateCustomer)
+			customers.GET("", controllers.GetCustomers)
+		}
+
+		// Blacklist flags on customer phones
+		flags := api.Group("/phone-flags")
+		flags.Use(middleware.RoleMiddleware("dispatcher"))
+		{
+			flags.GET("/:id/events", controllers.GetPhoneFlagEvents)
+			flags.PUT("/:id", controllers.UpdatePhoneFlag)
+			flags.DELETE("/:id", controllers.RemovePhoneFlag)
+			flags.POST("", controllers.FlagPhone)
+			flags.GET("", controllers.GetPhoneFlags)
+		}
+
+		// Fares and tariffs
+		api.POST("/fares/estimate", middleware.RoleMiddleware("dispatcher"), controllers.EstimateFare)
+		api.GET("/tariffs", middleware.RoleMiddleware("dispatcher"), controllers.GetTariffs)
+		api.POST("/tariffs", middleware.RoleMiddleware("dispatcher"), controllers.CreateTariff)
+		api.PUT("/tariffs/:id", middleware.RoleMiddleware("dispatcher"), controllers.UpdateTariff)
+
+		// Tariff zones; static paths before /:id
+		zones := api.Group("/zones")
+		{
+			zones.GET("/locate", controllers.LocateZone)
+			zones.GET("/fares", middleware.RoleMiddleware("dispatcher"), controllers.GetZoneFares)
+			zones.PUT("/fares", middleware.RoleMiddleware("dispatcher"), controllers.UpdateZoneFares)
+			zones.POST("/import", middleware.RoleMiddleware("dispatcher"), controllers.ImportZones)
+			zones.PUT("/:id", middleware.RoleMiddleware("dispatcher"), controllers.UpdateZone)
+			zones.DELETE("/:id", middleware.RoleMiddleware("dispatcher"), controllers.DeleteZone)
+			zones.POST("", middleware.RoleMiddleware("dispatcher"), controllers.CreateZone)
+			zones.GET("", middleware.RoleMiddleware("dispatcher"), controllers.GetZones)
+		}
+
+		// Auto-dispatch settings
+		api.GET("/dispatch/settings", middleware.RoleMiddleware("dispatcher"), controllers.GetDispatchSettings)
+		api.PUT("/dispatch/settings", middleware.RoleMiddleware("dispatcher"), controllers.UpdateDispatchSettings)
 
 		// Order Routes - более специфичные маршруты должны быть первыми
 		// Регистрируем маршруты с параметрами перед общими
 		ordersGroup := api.Group("/orders")
 		{
+			ordersGroup.GET("/history", controllers.GetOrderHistory)
 			ordersGroup.PUT("/:id/assign", middleware.RoleMiddleware("dispatcher"), controllers.AssignDriver)
-			ordersGroup.PUT("/:id/status", controllers.UpdateOrderStatus)
-			ordersGroup.POST("", middleware.RoleMiddleware("dispatcher"), controllers.CreateOrder)
+			ordersGroup.PUT("/:id/unassign", middleware.RoleMiddleware("dispatcher"), controllers.UnassignDriver)
+			ordersGroup.PUT("/:id/schedule", middleware.RoleMiddleware("dispatcher"), controllers.RescheduleOrder)
+			ordersGroup.PUT("/:id/status", middleware.Idempotency(), controllers.UpdateOrderStatus)
+			ordersGroup.PUT("/:id/reject", middleware.RoleMiddleware("driver"), controllers.RejectOrder)
+			ordersGroup.PUT("/:id/arrived", middleware.RoleMiddleware("driver"), controllers.MarkArrived)
+			ordersGroup.PUT("/:id/stops/:stopId/arrived", middleware.RoleMiddleware("driver"), controllers.MarkStopArrived)
+			ordersGroup.PUT("/:id/stops/:stopId/departed", middleware.RoleMiddleware("driver"), controllers.MarkStopDeparted)
+			ordersGroup.GET("/:id/events", middleware.RoleMiddleware("dispatcher"), controllers.GetOrderEvents)
+			ordersGroup.GET("/:id/candidates", middleware.RoleMiddleware("dispatcher"), controllers.GetOrderCandidates)
+			ordersGroup.POST("", middleware.RoleMiddleware("dispatcher"), middleware.Idempotency(), controllers.CreateOrder)
 			ordersGroup.GET("", controllers.GetOrders)
 		}
+
+		// Recurring order templates
+		templates := api.Group("/order-templates")
+		templates.Use(middleware.RoleMiddleware("dispatcher"))
+		{
+			templates.POST("", controllers.CreateOrderTemplate)
+			templates.GET("", controllers.GetOrderTemplates)
+			templates.PUT("/:id/pause", controllers.PauseOrderTemplate)
+			templates.PUT("/:id/resume", controllers.ResumeOrderTemplate)
+			templates.GET("/:id/occurrences", controllers.GetTemplateOccurrences)
+		}
+	}
+
+	// PBX webhooks, signed with TELEPHONY_SECRET instead of a user token
+	integrations := r.Group("/integrations")
+	{
+		integrations.POST("/telephony/call", middleware.TelephonySignature(), controllers.TelephonyCall)
+	}
+
+	// Real-time updates; browsers can't set headers on WebSocket/EventSource, so the token may come in the query
+	stream := r.Group("/api")
+	stream.Use(middleware.StreamAuthMiddleware())
+	{
+		stream.GET("/ws", controllers.OrderUpdatesSocket)
+		stream.GET("/stream", controllers.OrderUpdatesStream)
 	}
 
 	// Обработчик для несуществующих маршрутов
@@ -20,18 +20,41 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := authHeader[len(prefix):]
-		claims, err := utils.ValidateToken(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		authenticate(c, authHeader[len(prefix):])
+	}
+}
+
+// StreamAuthMiddleware is AuthMiddleware for WebSocket and SSE connections,
+// which browsers cannot open with custom headers: the token may also be passed
+// as the "token" query parameter.
+func StreamAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const prefix = "Bearer "
+		tokenString := c.Query("token")
+		if authHeader := c.GetHeader("Authorization"); strings.HasPrefix(authHeader, prefix) {
+			tokenString = authHeader[len(prefix):]
+		}
+		if tokenString == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			c.Abort()
 			return
 		}
 
-		c.Set("userID", claims.UserID)
-		c.Set("role", claims.Role)
-		c.Next()
+		authenticate(c, tokenString)
+	}
+}
+
+func authenticate(c *gin.Context, tokenString string) {
+	claims, err := utils.ValidateToken(tokenString)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return
 	}
+
+	c.Set("userID", claims.UserID)
+	c.Set("role", claims.Role)
+	c.Next()
 }
 
 func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
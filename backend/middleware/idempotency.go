@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/models"
+)
+
+// IdempotencyTTL is how long a key and its response are kept.
+const IdempotencyTTL = 24 * time.Hour
+
+// responseRecorder keeps a copy of the response body for storing.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response when a request is retried with the
+// same Idempotency-Key header. Keys are scoped per user; reusing a key with a
+// different request is rejected with 422. Must run after AuthMiddleware.
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			c.Abort()
+			return
+		}
+		userID, _ := c.Get("userID")
+		uid, _ := userID.(uint)
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.New()
+		sum.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		sum.Write(body)
+		hash := hex.EncodeToString(sum.Sum(nil))
+
+		var stored models.IdempotencyKey
+		err = database.DB.Where("user_id = ? AND key = ?", uid, key).First(&stored).Error
+		switch {
+		case err == nil && stored.ExpiresAt.Before(time.Now()):
+			database.DB.Delete(&stored)
+		case err == nil:
+			replayIdempotent(c, &stored, hash)
+			return
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check Idempotency-Key"})
+			c.Abort()
+			return
+		}
+
+		record := models.IdempotencyKey{
+			UserID:      uid,
+			Key:         key,
+			RequestHash: hash,
+			ExpiresAt:   time.Now().Add(IdempotencyTTL),
+		}
+		if err := database.DB.Create(&record).Error; err != nil {
+			// Lost the race against a concurrent retry with the same key.
+			c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is already in progress"})
+			c.Abort()
+			return
+		}
+
+		// Unless a response gets stored, the key is released so the client can
+		// retry with it; this also runs when the handler panics.
+		saved := false
+		defer func() {
+			if !saved {
+				database.DB.Delete(&record)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			// Let the client retry failures with the same key.
+			return
+		}
+		err = database.DB.Model(&record).Updates(models.IdempotencyKey{
+			StatusCode:  status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			ETag:        recorder.Header().Get("ETag"),
+			Body:        recorder.body.Bytes(),
+		}).Error
+		if err != nil {
+			log.Printf("Idempotency: could not store response for key %q: %v", key, err)
+			return
+		}
+		saved = true
+	}
+}
+
+func replayIdempotent(c *gin.Context, stored *models.IdempotencyKey, hash string) {
+	defer c.Abort()
+	if stored.RequestHash != hash {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+		return
+	}
+	if stored.StatusCode == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is already in progress"})
+		return
+	}
+	if stored.ETag != "" {
+		c.Header("ETag", stored.ETag)
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(stored.StatusCode, stored.ContentType, stored.Body)
+}
+
+// PurgeIdempotencyKeys deletes expired keys every interval until ctx is cancelled.
+func PurgeIdempotencyKeys(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := database.DB.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{}).Error; err != nil {
+				log.Printf("Idempotency: purge failed: %v", err)
+			}
+		}
+	}
+}
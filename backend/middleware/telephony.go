@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taxi-fleet-backend/utils"
+)
+
+// telephonyMaxSkew bounds the age of a signed webhook request to stop replays.
+const telephonyMaxSkew = 5 * time.Minute
+
+// TelephonySignature authenticates PBX webhooks with the shared TELEPHONY_SECRET.
+// The PBX sends the unix time in X-Telephony-Timestamp and
+// "sha256=" + SignWebhook(secret, timestamp, body) in X-Telephony-Signature.
+// Without a secret the webhook is disabled.
+func TelephonySignature() gin.HandlerFunc {
+	secret := os.Getenv("TELEPHONY_SECRET")
+	if secret == "" {
+		log.Println("TELEPHONY_SECRET is not set, telephony webhook disabled")
+	}
+	return func(c *gin.Context) {
+		if secret == "" {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Telephony integration is not configured"})
+			c.Abort()
+			return
+		}
+
+		timestamp := c.GetHeader("X-Telephony-Timestamp")
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid X-Telephony-Timestamp"})
+			c.Abort()
+			return
+		}
+		if skew := time.Since(time.Unix(unix, 0)); skew > telephonyMaxSkew || skew < -telephonyMaxSkew {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Request timestamp is too far from server time"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		signature := strings.TrimPrefix(c.GetHeader("X-Telephony-Signature"), "sha256=")
+		if !utils.VerifyWebhook(secret, timestamp, body, signature) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
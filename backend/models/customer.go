@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// CustomerAddress is an address the customer often rides from or to.
+type CustomerAddress struct {
+	Label   string   `json:"label"` // e.g. "Home", "Work"
+	Address string   `json:"address"`
+	Lat     *float64 `json:"lat"`
+	Lon     *float64 `json:"lon"`
+}
+
+// Customer is a passenger known by phone number.
+type Customer struct {
+	ID              uint              `gorm:"primaryKey" json:"id"`
+	Name            string            `json:"name"`
+	Phone           string            `gorm:"uniqueIndex" json:"phone"` // Normalized, see NormalizePhone
+	Notes           string            `json:"notes"`
+	Language        string            `json:"language"` // ru, kk or en for SMS; empty for the default
+	Addresses       []CustomerAddress `gorm:"serializer:json" json:"addresses"`
+	Blacklisted     bool              `json:"blacklisted"`
+	BlacklistReason string            `json:"blacklist_reason,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// NormalizePhone reduces a phone number to digits with the country code, so
+// "+7 (701) 123-45-67", "87011234567" and "7011234567" all become "77011234567".
+func NormalizePhone(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	switch {
+	case len(digits) == 10:
+		return "7" + digits
+	case len(digits) == 11 && digits[0] == '8':
+		return "7" + digits[1:]
+	}
+	return digits
+}
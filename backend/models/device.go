@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Device is a phone or browser of a user that receives push notifications.
+type Device struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"index" json:"user_id"`
+	Token      string    `gorm:"uniqueIndex" json:"token"` // FCM registration token
+	Platform   string    `json:"platform"`                 // android, ios or web
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKey remembers the response to a request sent with an
+// Idempotency-Key header so that retries get the same answer.
+type IdempotencyKey struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      uint   `gorm:"uniqueIndex:idx_idempotency_user_key"`
+	Key         string `gorm:"uniqueIndex:idx_idempotency_user_key;size:255"`
+	RequestHash string `gorm:"size:64"`
+	// StatusCode is 0 while the original request is still being processed.
+	StatusCode  int
+	ContentType string
+	ETag        string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time `gorm:"index"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// StaleLocationAfter is the age after which a driver's last fix is considered stale.
+const StaleLocationAfter = 2 * time.Minute
+
+// DriverLocation is the latest known position of a driver.
+type DriverLocation struct {
+	DriverID   uint      `gorm:"primaryKey;autoIncrement:false" json:"driver_id"`
+	Lat        float64   `json:"lat"`
+	Lon        float64   `json:"lon"`
+	Accuracy   float64   `json:"accuracy"` // meters
+	Speed      float64   `json:"speed"`    // m/s
+	Heading    float64   `json:"heading"`  // degrees from north
+	RecordedAt time.Time `json:"recorded_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// DriverLocationPoint is one fix of a driver's track history.
+type DriverLocationPoint struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	DriverID   uint      `gorm:"index:idx_driver_track,priority:1" json:"driver_id"`
+	Lat        float64   `json:"lat"`
+	Lon        float64   `json:"lon"`
+	Accuracy   float64   `json:"accuracy"`
+	Speed      float64   `json:"speed"`
+	Heading    float64   `json:"heading"`
+	RecordedAt time.Time `gorm:"index:idx_driver_track,priority:2" json:"recorded_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
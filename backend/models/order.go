@@ -2,11 +2,14 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type OrderStatus string
 
 const (
+	OrderScheduled  OrderStatus = "scheduled" // Pre-booked, kept out of the live queue until promoted
 	OrderNew        OrderStatus = "new"
 	OrderAssigned   OrderStatus = "assigned"
 	OrderAccepted   OrderStatus = "accepted"
@@ -15,14 +18,56 @@ const (
 	OrderCancelled  OrderStatus = "cancelled"
 )
 
+// RejectReason is why a driver handed an assigned order back.
+type RejectReason string
+
+const (
+	RejectVehicleProblem       RejectReason = "vehicle_problem"
+	RejectTooFar               RejectReason = "too_far"
+	RejectPassengerUnreachable RejectReason = "passenger_unreachable"
+	RejectPersonal             RejectReason = "personal"
+	RejectOther                RejectReason = "other"
+)
+
 type Order struct {
-	ID          uint        `gorm:"primaryKey" json:"id"`
-	FromAddress string      `json:"from_address"`
-	ToAddress   string      `json:"to_address"`
-	Comment     string      `json:"comment"`
-	DriverID    *uint       `json:"driver_id"`
-	Driver      *User       `json:"driver,omitempty"`
-	Status      OrderStatus `json:"status"`
-	CreatedAt   time.Time   `json:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at"`
+	ID            uint        `gorm:"primaryKey" json:"id"`
+	FromAddress   string      `json:"from_address"` // Address of the first stop
+	ToAddress     string      `json:"to_address"`   // Address of the last stop
+	PickupLat     *float64    `json:"pickup_lat"`   // Coordinates of the first stop
+	PickupLon     *float64    `json:"pickup_lon"`
+	Stops         []OrderStop `gorm:"constraint:OnDelete:CASCADE" json:"stops,omitempty"`
+	Comment       string      `json:"comment"`
+	CustomerID    *uint       `gorm:"index" json:"customer_id"`
+	Customer      *Customer   `json:"customer,omitempty"`
+	DriverID      *uint       `json:"driver_id"`
+	Driver        *User       `json:"driver,omitempty"`
+	Status        OrderStatus `json:"status"`
+	Version       uint        `gorm:"not null;default:1" json:"version"`  // Bumped on every save, see BeforeSave
+	ScheduledAt   *time.Time  `gorm:"index" json:"scheduled_at"`          // Pickup time of a pre-booked order
+	RemindedAt    *time.Time  `json:"reminded_at,omitempty"`              // When the reserved driver was reminded
+	TemplateID    *uint       `gorm:"index" json:"template_id,omitempty"` // Recurring template the order was generated from
+	TariffID      *uint       `json:"tariff_id"`
+	PickupZoneID  *uint       `gorm:"index" json:"pickup_zone_id"`
+	DropoffZoneID *uint       `gorm:"index" json:"dropoff_zone_id"`
+	QuotedFare    *Fare       `gorm:"serializer:json" json:"quoted_fare"` // Estimate shown when the order was taken
+	FinalFare     *Fare       `gorm:"serializer:json" json:"final_fare"`  // Calculated from trip data when the order is done
+	AcceptedAt    *time.Time  `json:"accepted_at"`                        // Driver accepted the order
+	ArrivedAt     *time.Time  `json:"arrived_at"`                         // Driver arrived at the pickup
+	PickedUpAt    *time.Time  `json:"picked_up_at"`                       // Passenger on board, the ride started
+	CompletedAt   *time.Time  `json:"completed_at"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+
+	Warnings []string `gorm:"-" json:"warnings,omitempty"` // Shown to the dispatcher who created the order, not stored
+}
+
+// HasPickupPoint reports whether the pickup coordinates are known.
+func (o *Order) HasPickupPoint() bool {
+	return o.PickupLat != nil && o.PickupLon != nil
+}
+
+// BeforeSave bumps the version for optimistic concurrency control.
+func (o *Order) BeforeSave(tx *gorm.DB) error {
+	o.Version++
+	return nil
 }
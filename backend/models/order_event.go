@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// OrderEvent is a single entry in an order's audit timeline.
+type OrderEvent struct {
+	ID           uint        `gorm:"primaryKey" json:"id"`
+	OrderID      uint        `gorm:"index" json:"order_id"`
+	ActorID      uint        `json:"actor_id"`
+	ActorRole    Role        `json:"actor_role"`
+	FromStatus   OrderStatus `json:"from_status"`
+	ToStatus     OrderStatus `json:"to_status"`
+	DriverBefore *uint       `json:"driver_before"`
+	DriverAfter  *uint       `json:"driver_after"`
+	Reason       string      `json:"reason,omitempty"`
+	Note         string      `json:"note,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// NewOrderEvent describes the change from (fromStatus, driverBefore) to the order's current state.
+func NewOrderEvent(order *Order, actorID uint, actorRole Role, fromStatus OrderStatus, driverBefore *uint, note string) *OrderEvent {
+	return &OrderEvent{
+		OrderID:      order.ID,
+		ActorID:      actorID,
+		ActorRole:    actorRole,
+		FromStatus:   fromStatus,
+		ToStatus:     order.Status,
+		DriverBefore: driverBefore,
+		DriverAfter:  order.DriverID,
+		Note:         note,
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+type OfferStatus string
+
+const (
+	OfferPending   OfferStatus = "pending"
+	OfferAccepted  OfferStatus = "accepted"
+	OfferRejected  OfferStatus = "rejected"
+	OfferExpired   OfferStatus = "expired"
+	OfferWithdrawn OfferStatus = "withdrawn"
+)
+
+// OrderOffer is an auto-dispatch proposal of an order to one driver, who has
+// until ExpiresAt to accept it.
+type OrderOffer struct {
+	ID          uint        `gorm:"primaryKey" json:"id"`
+	OrderID     uint        `gorm:"index" json:"order_id"`
+	DriverID    uint        `gorm:"index" json:"driver_id"`
+	Strategy    string      `json:"strategy"`
+	Status      OfferStatus `gorm:"index" json:"status"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+	RespondedAt *time.Time  `json:"responded_at"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
@@ -0,0 +1,116 @@
+package models
+
+import "fmt"
+
+// TransitionError is returned when a role tries to move an order into a status
+// that is not reachable from its current one.
+type TransitionError struct {
+	Role Role
+	From OrderStatus
+	To   OrderStatus
+}
+
+func (e *TransitionError) Error() string {
+	from := e.From
+	if from == "" {
+		from = "none"
+	}
+	return fmt.Sprintf("%s cannot move order from %s to %s", e.Role, from, e.To)
+}
+
+// UnknownStatusError is returned for status values the state machine does not know.
+type UnknownStatusError struct {
+	Status OrderStatus
+}
+
+func (e *UnknownStatusError) Error() string {
+	return fmt.Sprintf("unknown order status %q", e.Status)
+}
+
+// ActiveStatuses are the statuses in which an order occupies its driver.
+var ActiveStatuses = []OrderStatus{OrderAssigned, OrderAccepted, OrderInProgress}
+
+// Valid reports whether s is one of the known order statuses.
+func (s OrderStatus) Valid() bool {
+	switch s {
+	case OrderScheduled, OrderNew, OrderAssigned, OrderAccepted, OrderInProgress, OrderDone, OrderCancelled:
+		return true
+	}
+	return false
+}
+
+// Terminal reports whether no further transitions are possible from s.
+func (s OrderStatus) Terminal() bool {
+	return s == OrderDone || s == OrderCancelled
+}
+
+// OrderStateMachine holds the allowed order status transitions per role.
+// The empty status stands for "order does not exist yet".
+type OrderStateMachine struct {
+	transitions map[Role]map[OrderStatus][]OrderStatus
+}
+
+// NewOrderStateMachine builds the default taxi order flow:
+// new → assigned → accepted → in_progress → done, with cancellation by the dispatcher.
+// Dispatchers may change the reserved driver or time of a scheduled order.
+// Dispatchers and drivers may hand an assigned order back to the queue, and the
+// system role offers and withdraws orders for auto-dispatch and releases
+// scheduled orders.
+func NewOrderStateMachine() *OrderStateMachine {
+	return &OrderStateMachine{
+		transitions: map[Role]map[OrderStatus][]OrderStatus{
+			RoleDispatcher: {
+				"":              {OrderNew, OrderAssigned, OrderScheduled},
+				OrderScheduled:  {OrderScheduled, OrderCancelled},
+				OrderNew:        {OrderAssigned, OrderCancelled},
+				OrderAssigned:   {OrderAssigned, OrderNew, OrderCancelled},
+				OrderAccepted:   {OrderAssigned, OrderNew, OrderCancelled},
+				OrderInProgress: {OrderDone, OrderCancelled},
+			},
+			RoleDriver: {
+				OrderAssigned:   {OrderAccepted, OrderNew},
+				OrderAccepted:   {OrderInProgress},
+				OrderInProgress: {OrderDone},
+			},
+			RoleSystem: {
+				"":             {OrderScheduled},
+				OrderScheduled: {OrderNew, OrderAssigned},
+				OrderNew:       {OrderAssigned},
+				OrderAssigned:  {OrderNew},
+			},
+		},
+	}
+}
+
+// OrderFlow is the state machine used by the order controllers.
+var OrderFlow = NewOrderStateMachine()
+
+// Allow registers an additional transition for role.
+func (m *OrderStateMachine) Allow(role Role, from OrderStatus, to ...OrderStatus) {
+	if m.transitions[role] == nil {
+		m.transitions[role] = map[OrderStatus][]OrderStatus{}
+	}
+	m.transitions[role][from] = append(m.transitions[role][from], to...)
+}
+
+// Can checks whether role may move an order from one status to another.
+func (m *OrderStateMachine) Can(role Role, from, to OrderStatus) error {
+	if !to.Valid() {
+		return &UnknownStatusError{Status: to}
+	}
+	for _, next := range m.transitions[role][from] {
+		if next == to {
+			return nil
+		}
+	}
+	return &TransitionError{Role: role, From: from, To: to}
+}
+
+// Apply validates the transition and updates order.Status on success.
+func (m *OrderStateMachine) Apply(order *Order, role Role, to OrderStatus) error {
+	if err := m.Can(role, order.Status, to); err != nil {
+		return err
+	}
+	order.Status = to
+	return nil
+}
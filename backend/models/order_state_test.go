@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOrderFlowCan(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    Role
+		from    OrderStatus
+		to      OrderStatus
+		allowed bool
+	}{
+		{"dispatcher creates an order", RoleDispatcher, "", OrderNew, true},
+		{"dispatcher reassigns an accepted order", RoleDispatcher, OrderAccepted, OrderAssigned, true},
+		{"dispatcher cannot reopen a done order", RoleDispatcher, OrderDone, OrderNew, false},
+		{"driver accepts", RoleDriver, OrderAssigned, OrderAccepted, true},
+		{"driver hands the order back", RoleDriver, OrderAssigned, OrderNew, true},
+		{"driver cannot cancel", RoleDriver, OrderAccepted, OrderCancelled, false},
+		{"driver cannot skip accepting", RoleDriver, OrderAssigned, OrderInProgress, false},
+		{"system releases a scheduled order", RoleSystem, OrderScheduled, OrderNew, true},
+		{"system withdraws an offer", RoleSystem, OrderAssigned, OrderNew, true},
+		{"system cannot complete", RoleSystem, OrderInProgress, OrderDone, false},
+		{"unknown role has no transitions", Role("passenger"), OrderNew, OrderCancelled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := OrderFlow.Can(tt.role, tt.from, tt.to)
+			if tt.allowed {
+				if err != nil {
+					t.Fatalf("Can() = %v, want nil", err)
+				}
+				return
+			}
+			var te *TransitionError
+			if !errors.As(err, &te) {
+				t.Fatalf("Can() = %v, want *TransitionError", err)
+			}
+			if te.Role != tt.role || te.From != tt.from || te.To != tt.to {
+				t.Errorf("TransitionError = %+v, want %s %s→%s", te, tt.role, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestOrderFlowUnknownStatus(t *testing.T) {
+	err := OrderFlow.Can(RoleDispatcher, OrderNew, "parked")
+	var ue *UnknownStatusError
+	if !errors.As(err, &ue) {
+		t.Fatalf("Can() = %v, want *UnknownStatusError", err)
+	}
+	if ue.Status != "parked" {
+		t.Errorf("Status = %q, want %q", ue.Status, "parked")
+	}
+}
+
+func TestOrderFlowApply(t *testing.T) {
+	order := &Order{Status: OrderAssigned}
+	if err := OrderFlow.Apply(order, RoleDriver, OrderAccepted); err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+	if order.Status != OrderAccepted {
+		t.Errorf("Status = %s, want %s", order.Status, OrderAccepted)
+	}
+	if err := OrderFlow.Apply(order, RoleDriver, OrderDone); err == nil {
+		t.Fatal("Apply() accepted → done by driver succeeded, want error")
+	}
+	if order.Status != OrderAccepted {
+		t.Errorf("Status after a rejected transition = %s, want %s", order.Status, OrderAccepted)
+	}
+}
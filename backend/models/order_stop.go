@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+type StopType string
+
+const (
+	StopPickup   StopType = "pickup"
+	StopWaypoint StopType = "waypoint"
+	StopDropoff  StopType = "dropoff"
+)
+
+// OrderStop is one point of an order's route. Stops are ordered by Position,
+// starting with the pickup and ending with the dropoff.
+type OrderStop struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	OrderID    uint       `gorm:"index" json:"order_id"`
+	Position   int        `json:"position"`
+	Type       StopType   `json:"type"`
+	Address    string     `json:"address"`
+	Lat        *float64   `json:"lat"`
+	Lon        *float64   `json:"lon"`
+	Entrance   string     `json:"entrance,omitempty"`  // Подъезд
+	Apartment  string     `json:"apartment,omitempty"` // Квартира / офис
+	Notes      string     `json:"notes,omitempty"`
+	ArrivedAt  *time.Time `json:"arrived_at"`
+	DepartedAt *time.Time `json:"departed_at"`
+}
+
+// BuildRoute numbers the stops and sets their types by position.
+func BuildRoute(stops []OrderStop) []OrderStop {
+	for i := range stops {
+		stops[i].Position = i + 1
+		switch i {
+		case 0:
+			stops[i].Type = StopPickup
+		case len(stops) - 1:
+			stops[i].Type = StopDropoff
+		default:
+			stops[i].Type = StopWaypoint
+		}
+	}
+	return stops
+}
+
+// SimpleRoute is the two-stop route of an order given only by its addresses.
+func SimpleRoute(from, to string, pickupLat, pickupLon *float64) []OrderStop {
+	return BuildRoute([]OrderStop{
+		{Address: from, Lat: pickupLat, Lon: pickupLon},
+		{Address: to},
+	})
+}
+
+// SyncRouteSummary derives FromAddress, ToAddress and the pickup point from the
+// stops, which older clients still read.
+func (o *Order) SyncRouteSummary() {
+	if len(o.Stops) == 0 {
+		return
+	}
+	first, last := o.Stops[0], o.Stops[len(o.Stops)-1]
+	o.FromAddress = first.Address
+	o.ToAddress = last.Address
+	o.PickupLat, o.PickupLon = first.Lat, first.Lon
+}
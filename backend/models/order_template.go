@@ -0,0 +1,142 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OrderTemplate is a recurring booking (e.g. a corporate client's daily ride)
+// from which scheduled orders are generated ahead of time.
+type OrderTemplate struct {
+	ID          uint     `gorm:"primaryKey" json:"id"`
+	Name        string   `json:"name"`
+	FromAddress string   `json:"from_address"`
+	ToAddress   string   `json:"to_address"`
+	PickupLat   *float64 `json:"pickup_lat"`
+	PickupLon   *float64 `json:"pickup_lon"`
+	Comment     string   `json:"comment"`
+	DriverID    *uint    `json:"driver_id"` // Reserved driver for every occurrence, optional
+
+	// Recurrence rule, modelled on RRULE FREQ=WEEKLY;BYDAY=...
+	Weekdays   string   `json:"weekdays"`                          // BYDAY codes, e.g. "MO,TU,WE,TH,FR"
+	PickupTime string   `json:"pickup_time"`                       // HH:MM in Timezone
+	Timezone   string   `json:"timezone"`                          // IANA name, e.g. "Asia/Almaty"
+	StartsOn   string   `json:"starts_on"`                         // YYYY-MM-DD, first possible date
+	EndsOn     *string  `json:"ends_on"`                           // YYYY-MM-DD, last possible date (UNTIL)
+	Exceptions []string `gorm:"serializer:json" json:"exceptions"` // YYYY-MM-DD dates to skip (EXDATE)
+
+	Paused         bool       `json:"paused"`
+	GeneratedUntil *time.Time `json:"generated_until"` // Latest occurrence already turned into an order
+	CreatedByID    uint       `json:"created_by_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+const dateLayout = "2006-01-02"
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// recurrence is the parsed form of the template's rule.
+type recurrence struct {
+	days       map[time.Weekday]bool
+	hour, min  int
+	loc        *time.Location
+	start      time.Time
+	end        *time.Time
+	exceptions map[string]bool
+}
+
+func (t *OrderTemplate) parse() (*recurrence, error) {
+	r := &recurrence{days: map[time.Weekday]bool{}, exceptions: map[string]bool{}}
+
+	for _, code := range strings.Split(t.Weekdays, ",") {
+		day, ok := weekdayCodes[strings.ToUpper(strings.TrimSpace(code))]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", code)
+		}
+		r.days[day] = true
+	}
+
+	if _, err := fmt.Sscanf(t.PickupTime, "%d:%d", &r.hour, &r.min); err != nil ||
+		r.hour < 0 || r.hour > 23 || r.min < 0 || r.min > 59 {
+		return nil, fmt.Errorf("invalid pickup time %q", t.PickupTime)
+	}
+
+	r.loc = time.Local
+	if t.Timezone != "" {
+		loc, err := time.LoadLocation(t.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q", t.Timezone)
+		}
+		r.loc = loc
+	}
+
+	start, err := time.ParseInLocation(dateLayout, t.StartsOn, r.loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q", t.StartsOn)
+	}
+	r.start = start
+	if t.EndsOn != nil && *t.EndsOn != "" {
+		end, err := time.ParseInLocation(dateLayout, *t.EndsOn, r.loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end date %q", *t.EndsOn)
+		}
+		if end.Before(start) {
+			return nil, errors.New("end date is before start date")
+		}
+		r.end = &end
+	}
+
+	for _, d := range t.Exceptions {
+		if _, err := time.Parse(dateLayout, d); err != nil {
+			return nil, fmt.Errorf("invalid exception date %q", d)
+		}
+		r.exceptions[d] = true
+	}
+	return r, nil
+}
+
+// Validate checks the recurrence rule.
+func (t *OrderTemplate) Validate() error {
+	_, err := t.parse()
+	return err
+}
+
+// Occurrences returns the pickup times in (after, until], in chronological order.
+func (t *OrderTemplate) Occurrences(after, until time.Time) ([]time.Time, error) {
+	r, err := t.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	day := after.In(r.loc)
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, r.loc)
+	if day.Before(r.start) {
+		day = r.start
+	}
+
+	var result []time.Time
+	for ; !day.After(until); day = day.AddDate(0, 0, 1) {
+		if r.end != nil && day.After(*r.end) {
+			break
+		}
+		if !r.days[day.Weekday()] || r.exceptions[day.Format(dateLayout)] {
+			continue
+		}
+		at := time.Date(day.Year(), day.Month(), day.Day(), r.hour, r.min, 0, 0, r.loc)
+		if at.After(after) && !at.After(until) {
+			result = append(result, at)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestTemplateOccurrences(t *testing.T) {
+	endsOn := "2026-03-31"
+	tests := []struct {
+		name        string
+		tpl         OrderTemplate
+		after       string
+		until       string
+		want        []string
+		wantInvalid bool
+	}{
+		{
+			name:  "weekdays only",
+			tpl:   OrderTemplate{Weekdays: "MO,WE,FR", PickupTime: "08:30", Timezone: "Asia/Almaty", StartsOn: "2026-01-01"},
+			after: "2026-01-05T00:00:00+05:00",
+			until: "2026-01-11T23:59:00+05:00",
+			want:  []string{"2026-01-05T08:30:00+05:00", "2026-01-07T08:30:00+05:00", "2026-01-09T08:30:00+05:00"},
+		},
+		{
+			name:  "today's pickup already passed",
+			tpl:   OrderTemplate{Weekdays: "MO,TU", PickupTime: "08:30", Timezone: "Asia/Almaty", StartsOn: "2026-01-01"},
+			after: "2026-01-05T09:00:00+05:00",
+			until: "2026-01-06T23:59:00+05:00",
+			want:  []string{"2026-01-06T08:30:00+05:00"},
+		},
+		{
+			name:  "exceptions are skipped",
+			tpl:   OrderTemplate{Weekdays: "MO,TU,WE", PickupTime: "07:00", Timezone: "Asia/Almaty", StartsOn: "2026-01-01", Exceptions: []string{"2026-01-06"}},
+			after: "2026-01-05T00:00:00+05:00",
+			until: "2026-01-07T23:59:00+05:00",
+			want:  []string{"2026-01-05T07:00:00+05:00", "2026-01-07T07:00:00+05:00"},
+		},
+		{
+			name:  "not before the start date",
+			tpl:   OrderTemplate{Weekdays: "MO,TU,WE,TH,FR,SA,SU", PickupTime: "10:00", Timezone: "Asia/Almaty", StartsOn: "2026-01-10"},
+			after: "2026-01-08T00:00:00+05:00",
+			until: "2026-01-11T23:59:00+05:00",
+			want:  []string{"2026-01-10T10:00:00+05:00", "2026-01-11T10:00:00+05:00"},
+		},
+		{
+			name:  "keeps local time across a DST change and stops at the end date",
+			tpl:   OrderTemplate{Weekdays: "SA,SU,MO", PickupTime: "08:00", Timezone: "Europe/Berlin", StartsOn: "2026-03-01", EndsOn: &endsOn},
+			after: "2026-03-28T00:00:00+01:00",
+			until: "2026-04-06T23:59:00+02:00",
+			want:  []string{"2026-03-28T08:00:00+01:00", "2026-03-29T08:00:00+02:00", "2026-03-30T08:00:00+02:00"},
+		},
+		{
+			name:  "pickup inside the skipped DST hour",
+			tpl:   OrderTemplate{Weekdays: "SU", PickupTime: "02:30", Timezone: "Europe/Berlin", StartsOn: "2026-03-01"},
+			after: "2026-03-28T00:00:00+01:00",
+			until: "2026-03-30T00:00:00+02:00",
+			want:  []string{"2026-03-29T03:30:00+02:00"},
+		},
+		{
+			name:        "invalid weekday",
+			tpl:         OrderTemplate{Weekdays: "MO,XX", PickupTime: "08:00", StartsOn: "2026-01-01"},
+			after:       "2026-01-01T00:00:00Z",
+			until:       "2026-01-08T00:00:00Z",
+			wantInvalid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.tpl.Occurrences(mustTime(t, tt.after), mustTime(t, tt.until))
+			if tt.wantInvalid {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Occurrences: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %v", len(got), got, tt.want)
+			}
+			for i, w := range tt.want {
+				if want := mustTime(t, w); !got[i].Equal(want) {
+					t.Errorf("occurrence %d = %s, want %s", i, got[i].Format(time.RFC3339), w)
+				}
+			}
+		})
+	}
+}
+
+func mustTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	v, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// OrderTimers are durations in minutes derived from the order's timestamps.
+// A timer that is still running is measured up to now.
+type OrderTimers struct {
+	TimeToArrivalMin *float64 `json:"time_to_arrival_min"` // Accepted until arrived at the pickup
+	WaitingMin       *float64 `json:"waiting_min"`         // Arrived until the passenger was on board
+	RideMin          *float64 `json:"ride_min"`            // Passenger on board until done
+}
+
+// Timers computes the order's timers at now.
+func (o *Order) Timers(now time.Time) OrderTimers {
+	var t OrderTimers
+	if o.AcceptedAt != nil {
+		t.TimeToArrivalMin = o.minutesBetween(*o.AcceptedAt, o.ArrivedAt, now)
+	}
+	if o.ArrivedAt != nil {
+		t.WaitingMin = o.minutesBetween(*o.ArrivedAt, o.PickedUpAt, now)
+	}
+	if o.PickedUpAt != nil {
+		t.RideMin = o.minutesBetween(*o.PickedUpAt, o.CompletedAt, now)
+	}
+	return t
+}
+
+// minutesBetween measures from start to end, or to now while the order is open.
+// A timer left running on a closed order is not reported.
+func (o *Order) minutesBetween(start time.Time, end *time.Time, now time.Time) *float64 {
+	stop := now
+	switch {
+	case end != nil:
+		stop = *end
+	case o.Status.Terminal():
+		return nil
+	}
+	m := stop.Sub(start).Minutes()
+	if m < 0 {
+		m = 0
+	}
+	m = math.Round(m*10) / 10
+	return &m
+}
+
+// ClearDriverTimers resets the timestamps that belong to the current driver,
+// used when the order goes back to the queue or to another driver. The stop
+// marks are stored separately and must be cleared along with them.
+func (o *Order) ClearDriverTimers() {
+	o.AcceptedAt = nil
+	o.ArrivedAt = nil
+}
+
+// MarshalJSON adds the live timers to the order.
+func (o Order) MarshalJSON() ([]byte, error) {
+	type plain Order
+	return json.Marshal(struct {
+		plain
+		Timers OrderTimers `json:"timers"`
+	}{plain(o), o.Timers(time.Now())})
+}
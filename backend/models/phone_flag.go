@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+type FlagSeverity string
+
+const (
+	FlagWarning FlagSeverity = "warning" // Orders are taken, the dispatcher is warned
+	FlagBlock   FlagSeverity = "block"   // Orders are refused
+)
+
+// PhoneFlag marks a phone number whose owner was abusive or did not show up.
+// Removed flags are kept inactive for the audit trail.
+type PhoneFlag struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Phone       string       `gorm:"uniqueIndex" json:"phone"` // Normalized, see NormalizePhone
+	CustomerID  *uint        `json:"customer_id"`
+	Severity    FlagSeverity `json:"severity"`
+	Reason      string       `json:"reason"`
+	Active      bool         `gorm:"not null;default:true" json:"active"`
+	CreatedByID uint         `json:"created_by_id"`
+	UpdatedByID uint         `json:"updated_by_id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+type FlagAction string
+
+const (
+	FlagCreated FlagAction = "created"
+	FlagUpdated FlagAction = "updated"
+	FlagRemoved FlagAction = "removed"
+)
+
+// PhoneFlagEvent is an entry in a flag's audit trail, recording the flag as
+// it was after the change.
+type PhoneFlagEvent struct {
+	ID        uint         `gorm:"primaryKey" json:"id"`
+	FlagID    uint         `gorm:"index" json:"flag_id"`
+	Phone     string       `gorm:"index" json:"phone"`
+	Action    FlagAction   `json:"action"`
+	ActorID   uint         `json:"actor_id"`
+	Severity  FlagSeverity `json:"severity"`
+	Reason    string       `json:"reason"`
+	Note      string       `json:"note,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// NewPhoneFlagEvent records the flag's current state.
+func NewPhoneFlagEvent(flag *PhoneFlag, action FlagAction, actorID uint, note string) *PhoneFlagEvent {
+	return &PhoneFlagEvent{
+		FlagID:   flag.ID,
+		Phone:    flag.Phone,
+		Action:   action,
+		ActorID:  actorID,
+		Severity: flag.Severity,
+		Reason:   flag.Reason,
+		Note:     note,
+	}
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Tariff is a price list used by the fare engine. Amounts are in whole
+// currency units.
+type Tariff struct {
+	ID                    uint      `gorm:"primaryKey" json:"id"`
+	Name                  string    `gorm:"uniqueIndex" json:"name"`
+	BaseFare              float64   `json:"base_fare"` // Charged for boarding
+	PerKm                 float64   `json:"per_km"`
+	PerMinute             float64   `json:"per_minute"`
+	MinimumFare           float64   `json:"minimum_fare"`
+	FreeWaitingMinutes    float64   `json:"free_waiting_minutes"` // Waiting at pickup that is not billed
+	WaitingPerMinute      float64   `json:"waiting_per_minute"`
+	NightSurchargePercent float64   `json:"night_surcharge_percent"` // Added to the fare for trips starting at night
+	NightStart            string    `json:"night_start"`             // HH:MM, e.g. "22:00"
+	NightEnd              string    `json:"night_end"`               // HH:MM, e.g. "06:00"
+	AirportPickupFee      float64   `json:"airport_pickup_fee"`
+	AirportDropoffFee     float64   `json:"airport_dropoff_fee"`
+	Timezone              string    `json:"timezone"` // IANA name the night window is in, server local time if empty
+	IsDefault             bool      `json:"is_default"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// Fare is a computed price with its components. Orders store the quote made at
+// creation and the final fare calculated when the trip is done.
+type Fare struct {
+	TariffID       uint      `json:"tariff_id"`
+	DistanceKm     float64   `json:"distance_km"`
+	DurationMin    float64   `json:"duration_min"`
+	WaitingMin     float64   `json:"waiting_min"`
+	Base           float64   `json:"base"`
+	Distance       float64   `json:"distance"`
+	Time           float64   `json:"time"`
+	Waiting        float64   `json:"waiting"`
+	NightSurcharge float64   `json:"night_surcharge"`
+	AirportFee     float64   `json:"airport_fee"`
+	ZoneSurcharge  float64   `json:"zone_surcharge"`
+	FixedPrice     bool      `json:"fixed_price"` // Base is an inter-zone fixed price
+	PickupZoneID   *uint     `json:"pickup_zone_id"`
+	DropoffZoneID  *uint     `json:"dropoff_zone_id"`
+	MinimumTopUp   float64   `json:"minimum_top_up"` // Difference added to reach the minimum fare
+	Total          float64   `json:"total"`
+	Estimated      bool      `json:"estimated"` // Distance and duration are estimates, not trip data
+	CalculatedAt   time.Time `json:"calculated_at"`
+}
+
+// Validate checks the amounts and the night window.
+func (t *Tariff) Validate() error {
+	for name, v := range map[string]float64{
+		"base_fare":               t.BaseFare,
+		"per_km":                  t.PerKm,
+		"per_minute":              t.PerMinute,
+		"minimum_fare":            t.MinimumFare,
+		"free_waiting_minutes":    t.FreeWaitingMinutes,
+		"waiting_per_minute":      t.WaitingPerMinute,
+		"night_surcharge_percent": t.NightSurchargePercent,
+		"airport_pickup_fee":      t.AirportPickupFee,
+		"airport_dropoff_fee":     t.AirportDropoffFee,
+	} {
+		if v < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if _, err := t.location(); err != nil {
+		return err
+	}
+	if (t.NightStart == "") != (t.NightEnd == "") {
+		return fmt.Errorf("night_start and night_end must be set together")
+	}
+	if t.NightStart != "" {
+		if _, err := clockMinutes(t.NightStart); err != nil {
+			return err
+		}
+		if _, err := clockMinutes(t.NightEnd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsNight reports whether at falls into the night window, which may span midnight.
+func (t *Tariff) IsNight(at time.Time) bool {
+	if t.NightStart == "" || t.NightEnd == "" {
+		return false
+	}
+	start, err1 := clockMinutes(t.NightStart)
+	end, err2 := clockMinutes(t.NightEnd)
+	loc, err3 := t.location()
+	if err1 != nil || err2 != nil || err3 != nil || start == end {
+		return false
+	}
+	local := at.In(loc)
+	m := local.Hour()*60 + local.Minute()
+	if start < end {
+		return m >= start && m < end
+	}
+	return m >= start || m < end
+}
+
+func (t *Tariff) location() (*time.Location, error) {
+	if t.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", t.Timezone)
+	}
+	return loc, nil
+}
+
+// clockMinutes parses HH:MM into minutes after midnight.
+func clockMinutes(s string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return h*60 + m, nil
+}
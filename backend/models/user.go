@@ -12,6 +12,8 @@ type DriverStatus string
 const (
 	RoleDispatcher Role = "dispatcher"
 	RoleDriver     Role = "driver"
+	// RoleSystem marks automated actions (e.g. auto-dispatch); no user has it.
+	RoleSystem Role = "system"
 
 	StatusOffline DriverStatus = "offline"
 	StatusFree    DriverStatus = "free"
@@ -19,14 +21,19 @@ const (
 )
 
 type User struct {
-	ID           uint         `gorm:"primaryKey" json:"id"`
-	Name         string       `json:"name"`
-	Phone        string       `gorm:"uniqueIndex" json:"phone"`
-	Role         Role         `json:"role"`
-	DriverStatus DriverStatus `json:"driver_status"` // Only for drivers
-	AvatarURL    string       `json:"avatar_url,omitempty"` // URL фото (для будущей загрузки)
-	PasswordHash string       `json:"-"`
-	CreatedAt    time.Time    `json:"created_at"`
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	Name           string       `json:"name"`
+	Phone          string       `gorm:"uniqueIndex" json:"phone"`
+	Role           Role         `json:"role"`
+	DriverStatus   DriverStatus `json:"driver_status"`                             // Only for drivers
+	AvatarURL      string       `json:"avatar_url,omitempty"`                      // URL фото (для будущей загрузки)
+	RejectedOrders int          `gorm:"not null;default:0" json:"rejected_orders"` // Only for drivers
+	Extension      string       `gorm:"index" json:"extension,omitempty"`          // PBX extension of a dispatcher's phone
+	CarMake        string       `json:"car_make,omitempty"`                        // Only for drivers, e.g. "Toyota Camry"
+	CarColor       string       `json:"car_color,omitempty"`
+	CarPlate       string       `json:"car_plate,omitempty"`
+	PasswordHash   string       `json:"-"`
+	CreatedAt      time.Time    `json:"created_at"`
 }
 
 func (u *User) SetPassword(password string) error {
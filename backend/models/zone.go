@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Zone is a tariff area of the city such as downtown, the suburbs or the
+// airport, drawn as a GeoJSON polygon.
+type Zone struct {
+	ID               uint            `gorm:"primaryKey" json:"id"`
+	Name             string          `gorm:"uniqueIndex" json:"name"`
+	Geometry         json.RawMessage `gorm:"serializer:json" json:"geometry"` // GeoJSON Polygon or MultiPolygon
+	TariffID         *uint           `json:"tariff_id"`                       // Tariff for pickups in the zone, the default one if empty
+	PickupSurcharge  float64         `json:"pickup_surcharge"`
+	DropoffSurcharge float64         `json:"dropoff_surcharge"`
+	Priority         int             `json:"priority"` // Higher wins where zones overlap
+	Active           bool            `gorm:"not null;default:true" json:"active"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// ZoneFare is a fixed price between two zones, replacing the metered part of
+// the fare. The matrix is directional; add both directions for round trips.
+type ZoneFare struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	FromZoneID uint      `gorm:"uniqueIndex:idx_zone_fare_route" json:"from_zone_id"`
+	ToZoneID   uint      `gorm:"uniqueIndex:idx_zone_fare_route" json:"to_zone_id"`
+	Price      float64   `json:"price"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,250 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	fcmEndpoint = "https://fcm.googleapis.com"
+	fcmScope    = "https://www.googleapis.com/auth/firebase.messaging"
+	// tokenRefreshMargin renews the access token before it actually expires.
+	tokenRefreshMargin = time.Minute
+)
+
+// serviceAccount is the part of a Google service account key file FCM needs.
+type serviceAccount struct {
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// FCM sends pushes through the Firebase Cloud Messaging HTTP v1 API,
+// authenticating with a service account.
+type FCM struct {
+	ProjectID string
+	Endpoint  string
+	Client    *http.Client
+
+	account serviceAccount
+	key     *rsa.PrivateKey
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCM reads a service account key file (JSON).
+func NewFCM(credentials []byte) (*FCM, error) {
+	var account serviceAccount
+	if err := json.Unmarshal(credentials, &account); err != nil {
+		return nil, fmt.Errorf("fcm: invalid credentials: %w", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" || account.ProjectID == "" {
+		return nil, errors.New("fcm: credentials need project_id, client_email and private_key")
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("fcm: invalid private key: %w", err)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return &FCM{
+		ProjectID: account.ProjectID,
+		Endpoint:  fcmEndpoint,
+		Client:    &http.Client{Timeout: 10 * time.Second},
+		account:   account,
+		key:       key,
+	}, nil
+}
+
+// token returns a cached OAuth2 access token, exchanging a signed JWT for a
+// new one when it is about to expire.
+func (f *FCM) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.accessToken != "" && time.Until(f.expiresAt) > tokenRefreshMargin {
+		return f.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	assertion.Header["kid"] = f.account.PrivateKeyID
+	signed, err := assertion.SignedString(f.key)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {signed},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return "", &RetryableError{Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("fcm: token exchange returned %s: %s", resp.Status, bytes.TrimSpace(body))
+		if resp.StatusCode >= 500 {
+			return "", &RetryableError{Err: err}
+		}
+		return "", err
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("fcm: token exchange: %w", err)
+	}
+	f.accessToken = result.AccessToken
+	f.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
+
+type fcmRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+	Android      fcmAndroid        `json:"android"`
+	APNS         fcmAPNS           `json:"apns"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmAndroid struct {
+	Priority string `json:"priority"`
+}
+
+type fcmAPNS struct {
+	Headers map[string]string `json:"headers"`
+}
+
+type fcmError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			ErrorCode       string `json:"errorCode"`
+			FieldViolations []struct {
+				Field string `json:"field"`
+			} `json:"fieldViolations"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// badToken reports whether FCM rejected the request because of the
+// registration token itself, e.g. a malformed or truncated one.
+func (e *fcmError) badToken() bool {
+	for _, d := range e.Error.Details {
+		for _, v := range d.FieldViolations {
+			if v.Field == "message.token" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *FCM) Send(ctx context.Context, token string, msg PushMessage) error {
+	access, err := f.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	// High priority wakes the app in the background so the driver sees the order.
+	body, err := json.Marshal(fcmRequest{Message: fcmMessage{
+		Token:        token,
+		Notification: fcmNotification{Title: msg.Title, Body: msg.Body},
+		Data:         msg.Data,
+		Android:      fcmAndroid{Priority: "high"},
+		APNS:         fcmAPNS{Headers: map[string]string{"apns-priority": "10"}},
+	}})
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/messages:send", f.Endpoint, url.PathEscape(f.ProjectID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+access)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return &RetryableError{Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var fe fcmError
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	_ = json.Unmarshal(raw, &fe)
+	code := fe.Error.Status
+	for _, d := range fe.Error.Details {
+		if d.ErrorCode != "" {
+			code = d.ErrorCode
+		}
+	}
+	err = fmt.Errorf("fcm: %s %s: %s", resp.Status, code, fe.Error.Message)
+
+	switch {
+	case code == "UNREGISTERED" || code == "SENDER_ID_MISMATCH" || resp.StatusCode == http.StatusNotFound,
+		code == "INVALID_ARGUMENT" && fe.badToken():
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	case resp.StatusCode == http.StatusUnauthorized:
+		// The access token may have been revoked; fetch a new one on retry.
+		f.mu.Lock()
+		f.accessToken = ""
+		f.mu.Unlock()
+		return &RetryableError{Err: err}
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		retry := &RetryableError{Err: err}
+		if secs, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil {
+			retry.After = time.Duration(secs) * time.Second
+		}
+		return retry
+	}
+	return err
+}
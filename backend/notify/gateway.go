@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Message is the data available to gateway templates as {{.To}} and {{.Text}}.
+type Message struct {
+	To   string
+	Text string
+}
+
+// HTTPGateway sends SMS through a provider's HTTP API. The URL and body are
+// text/template templates over Message, with the functions urlquery (already
+// built in) and json, which renders a value as a JSON string literal:
+//
+//	URL:  https://sms.example.com/send?login=me&phone={{.To}}&text={{urlquery .Text}}
+//	Body: {"to": {{json .To}}, "message": {{json .Text}}}
+type HTTPGateway struct {
+	Method      string
+	URL         *template.Template
+	Body        *template.Template // Optional
+	ContentType string
+	Headers     map[string]string
+	Client      *http.Client
+}
+
+var gatewayFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// NewHTTPGateway parses the URL and body templates.
+func NewHTTPGateway(method, urlTemplate, bodyTemplate string) (*HTTPGateway, error) {
+	if urlTemplate == "" {
+		return nil, errors.New("gateway URL is empty")
+	}
+	g := &HTTPGateway{
+		Method:      strings.ToUpper(method),
+		ContentType: "application/json",
+		Headers:     map[string]string{},
+		Client:      &http.Client{Timeout: 10 * time.Second},
+	}
+	if g.Method == "" {
+		g.Method = http.MethodPost
+	}
+	var err error
+	if g.URL, err = template.New("url").Funcs(gatewayFuncs).Parse(urlTemplate); err != nil {
+		return nil, fmt.Errorf("gateway URL template: %w", err)
+	}
+	if bodyTemplate != "" {
+		if g.Body, err = template.New("body").Funcs(gatewayFuncs).Parse(bodyTemplate); err != nil {
+			return nil, fmt.Errorf("gateway body template: %w", err)
+		}
+	}
+	return g, nil
+}
+
+// GatewayFromEnv reads SMS_HTTP_URL, SMS_HTTP_METHOD (POST), SMS_HTTP_BODY,
+// SMS_HTTP_CONTENT_TYPE (application/json) and SMS_HTTP_HEADERS, a "|"
+// separated list of "Name: value" pairs such as an Authorization header.
+func GatewayFromEnv() (*HTTPGateway, error) {
+	g, err := NewHTTPGateway(os.Getenv("SMS_HTTP_METHOD"), os.Getenv("SMS_HTTP_URL"), os.Getenv("SMS_HTTP_BODY"))
+	if err != nil {
+		return nil, err
+	}
+	if v := os.Getenv("SMS_HTTP_CONTENT_TYPE"); v != "" {
+		g.ContentType = v
+	}
+	for _, h := range strings.Split(os.Getenv("SMS_HTTP_HEADERS"), "|") {
+		if strings.TrimSpace(h) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header %q in SMS_HTTP_HEADERS", h)
+		}
+		g.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return g, nil
+}
+
+func (g *HTTPGateway) Send(ctx context.Context, to, text string) error {
+	if text == "" {
+		return errEmptyMessage
+	}
+	msg := Message{To: to, Text: text}
+
+	var target bytes.Buffer
+	if err := g.URL.Execute(&target, msg); err != nil {
+		return fmt.Errorf("gateway URL: %w", err)
+	}
+	if _, err := url.Parse(target.String()); err != nil {
+		return fmt.Errorf("gateway URL: %w", err)
+	}
+	var body io.Reader
+	if g.Body != nil {
+		var buf bytes.Buffer
+		if err := g.Body.Execute(&buf, msg); err != nil {
+			return fmt.Errorf("gateway body: %w", err)
+		}
+		body = &buf
+	}
+
+	req, err := http.NewRequestWithContext(ctx, g.Method, target.String(), body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", g.ContentType)
+	}
+	for name, value := range g.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("gateway returned %s: %s", resp.Status, bytes.TrimSpace(reply))
+	}
+	return nil
+}
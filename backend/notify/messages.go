@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Kind is a passenger notification.
+type Kind string
+
+const (
+	DriverAccepted Kind = "accepted" // The driver accepted the order and is on the way
+	DriverArrived  Kind = "arrived"
+	TripCompleted  Kind = "completed"
+)
+
+// Languages supported by the message templates.
+var Languages = []string{"ru", "kk", "en"}
+
+// MessageData fills the message templates.
+type MessageData struct {
+	DriverName string
+	Car        string // e.g. "white Toyota Camry 123ABC02"
+	ETAMin     int    // 0 if unknown
+	Fare       float64
+}
+
+var messageTemplates = map[string]map[Kind]string{
+	"ru": {
+		DriverAccepted: "Водитель {{.DriverName}} принял ваш заказ{{if .Car}} ({{.Car}}){{end}}{{if .ETAMin}}, прибудет через {{.ETAMin}} мин{{end}}.",
+		DriverArrived:  "Водитель {{.DriverName}} ожидает вас{{if .Car}}: {{.Car}}{{end}}.",
+		TripCompleted:  "Поездка завершена{{if .Fare}}, стоимость {{fare .Fare}} ₸{{end}}. Спасибо!",
+	},
+	"kk": {
+		DriverAccepted: "Жүргізуші {{.DriverName}} тапсырысыңызды қабылдады{{if .Car}} ({{.Car}}){{end}}{{if .ETAMin}}, {{.ETAMin}} минуттан кейін келеді{{end}}.",
+		DriverArrived:  "Жүргізуші {{.DriverName}} сізді күтіп тұр{{if .Car}}: {{.Car}}{{end}}.",
+		TripCompleted:  "Сапар аяқталды{{if .Fare}}, құны {{fare .Fare}} ₸{{end}}. Рахмет!",
+	},
+	"en": {
+		DriverAccepted: "Driver {{.DriverName}} accepted your order{{if .Car}} ({{.Car}}){{end}}{{if .ETAMin}}, arriving in {{.ETAMin}} min{{end}}.",
+		DriverArrived:  "Your driver {{.DriverName}} is waiting{{if .Car}}: {{.Car}}{{end}}.",
+		TripCompleted:  "Your trip is complete{{if .Fare}}, fare {{fare .Fare}} ₸{{end}}. Thank you!",
+	},
+}
+
+var messages = parseMessages()
+
+func parseMessages() map[string]map[Kind]*template.Template {
+	funcs := template.FuncMap{
+		"fare": func(v float64) string { return fmt.Sprintf("%.0f", v) },
+	}
+	parsed := make(map[string]map[Kind]*template.Template, len(messageTemplates))
+	for lang, kinds := range messageTemplates {
+		parsed[lang] = make(map[Kind]*template.Template, len(kinds))
+		for kind, text := range kinds {
+			parsed[lang][kind] = template.Must(template.New(lang + "/" + string(kind)).Funcs(funcs).Parse(text))
+		}
+	}
+	return parsed
+}
+
+// SupportedLanguage reports whether there are messages in lang.
+func SupportedLanguage(lang string) bool {
+	_, ok := messages[lang]
+	return ok
+}
+
+// Render builds the message of the given kind in lang.
+func Render(lang string, kind Kind, data MessageData) (string, error) {
+	tmpl, ok := messages[lang][kind]
+	if !ok {
+		return "", fmt.Errorf("notify: no %s message in %q", kind, lang)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(buf.String()), " "), nil
+}
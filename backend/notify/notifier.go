@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/geo"
+	"taxi-fleet-backend/models"
+	"taxi-fleet-backend/pricing"
+	"taxi-fleet-backend/realtime"
+)
+
+const (
+	sendTimeout  = 15 * time.Second
+	sendAttempts = 3
+)
+
+// Notifier texts passengers about their order: when the driver accepts it,
+// arrives at the pickup and completes the trip. Nothing is sent on assignment,
+// so passengers never hear about offers that are then rejected.
+type Notifier struct {
+	Sender          SMSSender
+	DefaultLanguage string // For customers without a supported language
+}
+
+// New returns a notifier with the default language from SMS_DEFAULT_LANGUAGE (ru).
+func New(sender SMSSender) *Notifier {
+	lang := os.Getenv("SMS_DEFAULT_LANGUAGE")
+	if !SupportedLanguage(lang) {
+		lang = "ru"
+	}
+	return &Notifier{Sender: sender, DefaultLanguage: lang}
+}
+
+// Run sends notifications for order events from hub until ctx is cancelled.
+// It listens rather than subscribes, so a burst of events never loses an SMS.
+func (n *Notifier) Run(ctx context.Context, hub *realtime.Hub) {
+	stop := hub.Listen(func(e realtime.Event) {
+		if kind, ok := kindOf(e); ok {
+			go n.notify(e.Order.ID, kind)
+		}
+	})
+	defer stop()
+	<-ctx.Done()
+}
+
+func kindOf(e realtime.Event) (Kind, bool) {
+	if e.Order == nil {
+		return "", false
+	}
+	switch {
+	case e.Type == realtime.OrderStatusChanged && e.Order.Status == models.OrderAccepted:
+		return DriverAccepted, true
+	case e.Type == realtime.OrderDriverArrived:
+		return DriverArrived, true
+	case e.Type == realtime.OrderStatusChanged && e.Order.Status == models.OrderDone:
+		return TripCompleted, true
+	}
+	return "", false
+}
+
+func (n *Notifier) notify(orderID uint, kind Kind) {
+	var order models.Order
+	if err := database.DB.Preload("Customer").Preload("Driver").First(&order, orderID).Error; err != nil {
+		log.Printf("notify: loading order %d: %v", orderID, err)
+		return
+	}
+	if order.Customer == nil || order.Customer.Phone == "" {
+		return
+	}
+
+	data := MessageData{}
+	if order.Driver != nil {
+		data.DriverName = order.Driver.Name
+		data.Car = carDescription(order.Driver)
+	}
+	switch kind {
+	case DriverAccepted:
+		data.ETAMin = etaMinutes(&order)
+	case TripCompleted:
+		if order.FinalFare != nil {
+			data.Fare = order.FinalFare.Total
+		}
+	}
+
+	lang := order.Customer.Language
+	if !SupportedLanguage(lang) {
+		lang = n.DefaultLanguage
+	}
+	text, err := Render(lang, kind, data)
+	if err != nil {
+		log.Printf("notify: order %d: %v", orderID, err)
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err = n.Sender.Send(ctx, order.Customer.Phone, text)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt == sendAttempts {
+			log.Printf("notify: %s SMS for order %d failed: %v", kind, orderID, err)
+			return
+		}
+		time.Sleep(time.Duration(attempt) * 2 * time.Second)
+	}
+}
+
+// carDescription reads like "white Toyota Camry 123ABC02".
+func carDescription(driver *models.User) string {
+	var parts []string
+	for _, p := range []string{driver.CarColor, driver.CarMake, driver.CarPlate} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// etaMinutes estimates the drive from the driver's last fix to the pickup, or
+// returns 0 if either is unknown.
+func etaMinutes(order *models.Order) int {
+	if order.DriverID == nil || !order.HasPickupPoint() {
+		return 0
+	}
+	var loc models.DriverLocation
+	if err := database.DB.First(&loc, "driver_id = ?", *order.DriverID).Error; err != nil {
+		return 0
+	}
+	if time.Since(loc.RecordedAt) > models.StaleLocationAfter {
+		return 0
+	}
+	km := geo.Distance(geo.Point{Lat: loc.Lat, Lon: loc.Lon}, geo.Point{Lat: *order.PickupLat, Lon: *order.PickupLon}) / 1000
+	minutes := math.Ceil(km * pricing.RoadFactor / pricing.AverageSpeedKmh * 60)
+	return int(math.Max(1, minutes))
+}
@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// PushMessage is a notification shown on the device, with data for the app.
+type PushMessage struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// PushSender delivers a message to one device token.
+type PushSender interface {
+	Send(ctx context.Context, token string, msg PushMessage) error
+}
+
+// ErrInvalidToken is returned for tokens the push service no longer accepts,
+// e.g. after the app was uninstalled. Such devices should be forgotten.
+var ErrInvalidToken = errors.New("notify: device token is no longer valid")
+
+// RetryableError is a temporary failure; After is the delay the push service
+// asked for, zero if it did not say.
+type RetryableError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryableError) Error() string { return e.Err.Error() }
+func (e *RetryableError) Unwrap() error { return e.Err }
+
+// SentPush is a message recorded by FakePush.
+type SentPush struct {
+	Token   string
+	Message PushMessage
+	At      time.Time
+}
+
+// FakePush keeps pushes in memory instead of sending them, for development
+// and tests. Tokens listed in Invalid are rejected with ErrInvalidToken.
+type FakePush struct {
+	Invalid map[string]bool
+
+	mu   sync.Mutex
+	sent []SentPush
+}
+
+func NewFakePush() *FakePush {
+	return &FakePush{Invalid: map[string]bool{}}
+}
+
+func (f *FakePush) Send(ctx context.Context, token string, msg PushMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Invalid[token] {
+		return ErrInvalidToken
+	}
+	f.sent = append(f.sent, SentPush{Token: token, Message: msg, At: time.Now()})
+	log.Printf("push to %.12s…: %s: %s", token, msg.Title, msg.Body)
+	return nil
+}
+
+// Sent returns the pushes recorded so far.
+func (f *FakePush) Sent() []SentPush {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]SentPush(nil), f.sent...)
+}
+
+// PushFromEnv builds the sender selected by PUSH_SENDER:
+//   - "fcm": Firebase Cloud Messaging with the service account in FCM_CREDENTIALS
+//   - "fake": in-memory, logs every push
+//
+// An empty PUSH_SENDER disables push notifications.
+func PushFromEnv() (PushSender, error) {
+	switch kind := os.Getenv("PUSH_SENDER"); kind {
+	case "":
+		return nil, nil
+	case "fake":
+		return NewFakePush(), nil
+	case "fcm":
+		path := os.Getenv("FCM_CREDENTIALS")
+		if path == "" {
+			return nil, errors.New("FCM_CREDENTIALS is not set")
+		}
+		credentials, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewFCM(credentials)
+	default:
+		return nil, fmt.Errorf("unknown PUSH_SENDER %q", kind)
+	}
+}
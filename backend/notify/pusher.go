@@ -0,0 +1,158 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/models"
+)
+
+const (
+	pushAttempts   = 4
+	pushBackoff    = time.Second
+	pushMaxBackoff = 30 * time.Second
+)
+
+// PushKind is a driver notification.
+type PushKind string
+
+const (
+	PushAssigned   PushKind = "order_assigned"   // The order was given to the driver
+	PushUnassigned PushKind = "order_unassigned" // The order was taken away, e.g. reassigned or returned to the queue
+	PushCancelled  PushKind = "order_cancelled"
+	PushExpired    PushKind = "offer_expired"  // An auto-dispatch offer was not answered in time
+	PushReminder   PushKind = "order_reminder" // A reserved scheduled order is coming up
+)
+
+var pushTexts = map[string]map[PushKind][2]string{
+	"ru": {
+		PushAssigned:   {"Новый заказ", "Заказ #%d: %s → %s"},
+		PushUnassigned: {"Заказ снят", "Заказ #%d снят с вас диспетчером"},
+		PushCancelled:  {"Заказ отменён", "Заказ #%d: %s отменён диспетчером"},
+		PushExpired:    {"Время вышло", "Заказ #%d передан следующему водителю"},
+		PushReminder:   {"Предварительный заказ", "Заказ #%d в %s: %s"},
+	},
+	"kk": {
+		PushAssigned:   {"Жаңа тапсырыс", "Тапсырыс #%d: %s → %s"},
+		PushUnassigned: {"Тапсырыс алынды", "Тапсырыс #%d сізден диспетчер алып тастады"},
+		PushCancelled:  {"Тапсырыс тоқтатылды", "Тапсырыс #%d: %s диспетчер тоқтатты"},
+		PushExpired:    {"Уақыт бітті", "Тапсырыс #%d келесі жүргізушіге берілді"},
+		PushReminder:   {"Алдын ала тапсырыс", "Тапсырыс #%d сағат %s: %s"},
+	},
+	"en": {
+		PushAssigned:   {"New order", "Order #%d: %s → %s"},
+		PushUnassigned: {"Order withdrawn", "Order #%d was taken off you by the dispatcher"},
+		PushCancelled:  {"Order cancelled", "Order #%d: %s was cancelled by the dispatcher"},
+		PushExpired:    {"Offer expired", "Order #%d went to the next driver"},
+		PushReminder:   {"Upcoming booking", "Order #%d at %s: %s"},
+	},
+}
+
+// Pusher notifies drivers on all their registered devices.
+type Pusher struct {
+	Sender   PushSender
+	Language string
+}
+
+// DefaultPusher is set from main; nil when push notifications are disabled.
+var DefaultPusher *Pusher
+
+// NewPusher uses the language from PUSH_LANGUAGE (ru) for driver messages.
+func NewPusher(sender PushSender) *Pusher {
+	lang := os.Getenv("PUSH_LANGUAGE")
+	if _, ok := pushTexts[lang]; !ok {
+		lang = "ru"
+	}
+	return &Pusher{Sender: sender, Language: lang}
+}
+
+// Message builds the push for the order in the pusher's language.
+func (p *Pusher) Message(kind PushKind, order *models.Order) PushMessage {
+	text := pushTexts[p.Language][kind]
+	var body string
+	switch kind {
+	case PushAssigned:
+		body = fmt.Sprintf(text[1], order.ID, order.FromAddress, order.ToAddress)
+	case PushUnassigned, PushExpired:
+		body = fmt.Sprintf(text[1], order.ID)
+	case PushReminder:
+		var at string
+		if order.ScheduledAt != nil {
+			at = order.ScheduledAt.Local().Format("15:04")
+		}
+		body = fmt.Sprintf(text[1], order.ID, at, order.FromAddress)
+	default:
+		body = fmt.Sprintf(text[1], order.ID, order.FromAddress)
+	}
+	return PushMessage{
+		Title: text[0],
+		Body:  body,
+		Data: map[string]string{
+			"type":     string(kind),
+			"order_id": strconv.FormatUint(uint64(order.ID), 10),
+			"status":   string(order.Status),
+		},
+	}
+}
+
+// NotifyDriver pushes to every device of the driver in the background. The
+// message is built right away, so order may change after the call. A nil
+// pusher (push disabled) does nothing.
+func (p *Pusher) NotifyDriver(driverID uint, kind PushKind, order *models.Order) {
+	if p == nil {
+		return
+	}
+	msg := p.Message(kind, order)
+	go p.deliver(driverID, msg)
+}
+
+func (p *Pusher) deliver(userID uint, msg PushMessage) {
+	var devices []models.Device
+	if err := database.DB.Where("user_id = ?", userID).Find(&devices).Error; err != nil {
+		log.Printf("push: loading devices of user %d: %v", userID, err)
+		return
+	}
+	for _, d := range devices {
+		err := p.send(d.Token, msg)
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			log.Printf("push: forgetting device %d of user %d: %v", d.ID, userID, err)
+			if err := database.DB.Delete(&models.Device{}, d.ID).Error; err != nil {
+				log.Printf("push: deleting device %d: %v", d.ID, err)
+			}
+		case err != nil:
+			log.Printf("push: %s to device %d of user %d failed: %v", msg.Data["type"], d.ID, userID, err)
+		}
+	}
+}
+
+// send retries temporary failures with exponential backoff, or after the
+// delay the push service asked for.
+func (p *Pusher) send(token string, msg PushMessage) error {
+	backoff := pushBackoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err := p.Sender.Send(ctx, token, msg)
+		cancel()
+
+		var retry *RetryableError
+		if err == nil || !errors.As(err, &retry) || attempt == pushAttempts {
+			return err
+		}
+		wait := backoff
+		if retry.After > 0 {
+			wait = retry.After
+		}
+		if wait > pushMaxBackoff {
+			wait = pushMaxBackoff
+		}
+		time.Sleep(wait)
+		backoff *= 2
+	}
+}
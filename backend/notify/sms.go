@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMSSender delivers a text message to a phone number (digits with country code).
+type SMSSender interface {
+	Send(ctx context.Context, to, text string) error
+}
+
+// LogSender is the development sender: messages are written to the server log,
+// or appended to Path if it is set.
+type LogSender struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (s *LogSender) Send(ctx context.Context, to, text string) error {
+	if s.Path == "" {
+		log.Printf("sms to %s: %s", to, text)
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), to, strings.ReplaceAll(text, "\n", " "))
+	return err
+}
+
+// SMSFromEnv builds the sender selected by SMS_SENDER:
+//   - "log": server log, or SMS_LOG_PATH if set
+//   - "http": HTTP gateway, see GatewayFromEnv
+//
+// An empty SMS_SENDER disables SMS.
+func SMSFromEnv() (SMSSender, error) {
+	switch kind := os.Getenv("SMS_SENDER"); kind {
+	case "":
+		return nil, nil
+	case "log":
+		return &LogSender{Path: os.Getenv("SMS_LOG_PATH")}, nil
+	case "http":
+		return GatewayFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown SMS_SENDER %q", kind)
+	}
+}
+
+var errEmptyMessage = errors.New("notify: empty message")
@@ -0,0 +1,73 @@
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"taxi-fleet-backend/geo"
+)
+
+// defaultAirportRadius is used when PRICING_AIRPORTS gives no radius.
+const defaultAirportRadius = 2000.0
+
+// Airport is an area whose pickups and dropoffs carry the tariff's airport fees.
+type Airport struct {
+	Name    string
+	Point   geo.Point
+	RadiusM float64
+}
+
+// Airports is set from main, see AirportsFromEnv.
+var Airports []Airport
+
+// AirportsFromEnv parses PRICING_AIRPORTS, a semicolon separated list of
+// name:lat,lon[:radius_m], e.g. "ALA:43.3521,77.0405:3000".
+func AirportsFromEnv() ([]Airport, error) {
+	return ParseAirports(os.Getenv("PRICING_AIRPORTS"))
+}
+
+// ParseAirports parses the PRICING_AIRPORTS format.
+func ParseAirports(s string) ([]Airport, error) {
+	var airports []Airport
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid airport %q", item)
+		}
+		coords := strings.Split(parts[1], ",")
+		if len(coords) != 2 {
+			return nil, fmt.Errorf("invalid airport coordinates %q", parts[1])
+		}
+		lat, err1 := strconv.ParseFloat(strings.TrimSpace(coords[0]), 64)
+		lon, err2 := strconv.ParseFloat(strings.TrimSpace(coords[1]), 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid airport coordinates %q", parts[1])
+		}
+		airport := Airport{Name: strings.TrimSpace(parts[0]), Point: geo.Point{Lat: lat, Lon: lon}, RadiusM: defaultAirportRadius}
+		if len(parts) == 3 {
+			radius, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+			if err != nil || radius <= 0 {
+				return nil, fmt.Errorf("invalid airport radius %q", parts[2])
+			}
+			airport.RadiusM = radius
+		}
+		airports = append(airports, airport)
+	}
+	return airports, nil
+}
+
+// AtAirport reports whether p lies within any configured airport.
+func AtAirport(p geo.Point) bool {
+	for _, a := range Airports {
+		if geo.Distance(a.Point, p) <= a.RadiusM {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package pricing
+
+import (
+	"math"
+	"time"
+
+	"taxi-fleet-backend/geo"
+	"taxi-fleet-backend/models"
+)
+
+// Trip is what a fare is calculated from, either estimated from the route or
+// measured during the ride.
+type Trip struct {
+	DistanceKm     float64
+	DurationMin    float64
+	WaitingMin     float64
+	StartAt        time.Time // Decides whether the night surcharge applies
+	AirportPickup  bool
+	AirportDropoff bool
+	Estimated      bool
+
+	Pickup      *geo.Point
+	Dropoff     *geo.Point
+	PickupZone  *models.Zone // Set by PrepareTrip
+	DropoffZone *models.Zone
+	FixedPrice  *float64 // Inter-zone price replacing base, distance and time
+}
+
+// Calculate prices trip with tariff t. Components are rounded to cents and the
+// total to whole currency units. A fixed inter-zone price is not raised to the
+// tariff's minimum fare.
+func Calculate(t *models.Tariff, trip Trip) *models.Fare {
+	fare := &models.Fare{
+		TariffID:     t.ID,
+		DistanceKm:   round2(trip.DistanceKm),
+		DurationMin:  round2(trip.DurationMin),
+		WaitingMin:   round2(trip.WaitingMin),
+		Estimated:    trip.Estimated,
+		CalculatedAt: time.Now(),
+	}
+	if trip.FixedPrice != nil {
+		fare.Base = *trip.FixedPrice
+		fare.FixedPrice = true
+	} else {
+		fare.Base = t.BaseFare
+		fare.Distance = round2(trip.DistanceKm * t.PerKm)
+		fare.Time = round2(trip.DurationMin * t.PerMinute)
+	}
+	if trip.PickupZone != nil {
+		fare.PickupZoneID = &trip.PickupZone.ID
+		fare.ZoneSurcharge += trip.PickupZone.PickupSurcharge
+	}
+	if trip.DropoffZone != nil {
+		fare.DropoffZoneID = &trip.DropoffZone.ID
+		fare.ZoneSurcharge += trip.DropoffZone.DropoffSurcharge
+	}
+	if billed := trip.WaitingMin - t.FreeWaitingMinutes; billed > 0 {
+		fare.Waiting = round2(billed * t.WaitingPerMinute)
+	}
+
+	subtotal := fare.Base + fare.Distance + fare.Time + fare.Waiting
+	if t.IsNight(trip.StartAt) {
+		fare.NightSurcharge = round2(subtotal * t.NightSurchargePercent / 100)
+	}
+	if trip.AirportPickup {
+		fare.AirportFee += t.AirportPickupFee
+	}
+	if trip.AirportDropoff {
+		fare.AirportFee += t.AirportDropoffFee
+	}
+
+	total := subtotal + fare.NightSurcharge + fare.AirportFee + fare.ZoneSurcharge
+	if !fare.FixedPrice && total < t.MinimumFare {
+		fare.MinimumTopUp = round2(t.MinimumFare - total)
+		total = t.MinimumFare
+	}
+	fare.Total = math.Round(total)
+	return fare
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
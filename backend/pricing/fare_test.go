@@ -0,0 +1,124 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"taxi-fleet-backend/models"
+)
+
+func TestCalculate(t *testing.T) {
+	tariff := &models.Tariff{
+		ID:                    1,
+		BaseFare:              300,
+		PerKm:                 100,
+		PerMinute:             10,
+		MinimumFare:           800,
+		FreeWaitingMinutes:    3,
+		WaitingPerMinute:      20,
+		NightSurchargePercent: 20,
+		NightStart:            "22:00",
+		NightEnd:              "06:00",
+		AirportPickupFee:      1000,
+		AirportDropoffFee:     500,
+		Timezone:              "Asia/Almaty",
+	}
+	almaty, err := time.LoadLocation("Asia/Almaty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(hour, min int) time.Time {
+		return time.Date(2026, 3, 10, hour, min, 0, 0, almaty)
+	}
+	fixed := func(v float64) *float64 { return &v }
+	center := &models.Zone{ID: 7, PickupSurcharge: 100, DropoffSurcharge: 50}
+
+	tests := []struct {
+		name      string
+		trip      Trip
+		want      float64
+		wantTopUp float64
+		wantNight float64
+		wantFixed bool
+	}{
+		{
+			name: "day trip",
+			trip: Trip{DistanceKm: 5, DurationMin: 10, StartAt: at(12, 0)},
+			want: 900,
+		},
+		{
+			name:      "short trip is raised to the minimum fare",
+			trip:      Trip{DistanceKm: 1, DurationMin: 2, StartAt: at(12, 0)},
+			want:      800,
+			wantTopUp: 380,
+		},
+		{
+			name: "waiting beyond the free minutes is billed",
+			trip: Trip{DistanceKm: 5, DurationMin: 10, WaitingMin: 8, StartAt: at(12, 0)},
+			want: 1000,
+		},
+		{
+			name:      "night window before midnight",
+			trip:      Trip{DistanceKm: 5, DurationMin: 10, StartAt: at(23, 30)},
+			want:      1080,
+			wantNight: 180,
+		},
+		{
+			name:      "night window after midnight",
+			trip:      Trip{DistanceKm: 5, DurationMin: 10, StartAt: at(5, 59)},
+			want:      1080,
+			wantNight: 180,
+		},
+		{
+			name: "night window end is exclusive",
+			trip: Trip{DistanceKm: 5, DurationMin: 10, StartAt: at(6, 0)},
+			want: 900,
+		},
+		{
+			name:      "night window uses the tariff timezone",
+			trip:      Trip{DistanceKm: 5, DurationMin: 10, StartAt: time.Date(2026, 3, 10, 17, 30, 0, 0, time.UTC)},
+			want:      1080,
+			wantNight: 180,
+		},
+		{
+			name: "airport fees and zone surcharges",
+			trip: Trip{DistanceKm: 5, DurationMin: 10, StartAt: at(12, 0), AirportPickup: true, DropoffZone: center},
+			want: 1950,
+		},
+		{
+			name:      "fixed price is not raised to the minimum fare",
+			trip:      Trip{DistanceKm: 30, DurationMin: 40, StartAt: at(12, 0), FixedPrice: fixed(500), PickupZone: center},
+			want:      600,
+			wantFixed: true,
+		},
+		{
+			name:      "fixed price still gets the night surcharge",
+			trip:      Trip{DistanceKm: 30, DurationMin: 40, StartAt: at(23, 0), FixedPrice: fixed(1500)},
+			want:      1800,
+			wantNight: 300,
+			wantFixed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fare := Calculate(tariff, tt.trip)
+			if fare.Total != tt.want {
+				t.Errorf("Total = %v, want %v (%+v)", fare.Total, tt.want, fare)
+			}
+			if fare.MinimumTopUp != tt.wantTopUp {
+				t.Errorf("MinimumTopUp = %v, want %v", fare.MinimumTopUp, tt.wantTopUp)
+			}
+			if fare.NightSurcharge != tt.wantNight {
+				t.Errorf("NightSurcharge = %v, want %v", fare.NightSurcharge, tt.wantNight)
+			}
+			if fare.FixedPrice != tt.wantFixed {
+				t.Errorf("FixedPrice = %v, want %v", fare.FixedPrice, tt.wantFixed)
+			}
+			if tt.wantFixed && (fare.Distance != 0 || fare.Time != 0) {
+				t.Errorf("fixed price fare has distance %v and time %v", fare.Distance, fare.Time)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package pricing
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"taxi-fleet-backend/models"
+)
+
+// QuoteOrder syncs the route summary of an order with located stops and quotes
+// the fare for the pickup time with the given tariff, or the one picked by
+// PrepareTrip. Orders are still taken without a price while no tariff exists,
+// so ErrNoTariff is only returned for an explicit tariffID.
+func QuoteOrder(db *gorm.DB, order *models.Order, tariffID *uint) error {
+	order.SyncRouteSummary()
+
+	start := time.Now()
+	if order.ScheduledAt != nil {
+		start = *order.ScheduledAt
+	}
+	quote, err := QuoteRoute(db, tariffID, order.Stops, start)
+	if errors.Is(err, ErrNoTariff) && tariffID == nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	order.TariffID = &quote.Tariff.ID
+	order.QuotedFare = quote.Fare
+	if quote.Trip.PickupZone != nil {
+		order.PickupZoneID = &quote.Trip.PickupZone.ID
+	}
+	if quote.Trip.DropoffZone != nil {
+		order.DropoffZoneID = &quote.Trip.DropoffZone.ID
+	}
+	return nil
+}
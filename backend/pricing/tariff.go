@@ -0,0 +1,67 @@
+package pricing
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"taxi-fleet-backend/models"
+)
+
+// ErrNoTariff is returned when no tariff is given and none is marked default.
+var ErrNoTariff = errors.New("no tariff configured")
+
+// LoadTariff returns the tariff with the given ID, or the default one if id is nil.
+func LoadTariff(db *gorm.DB, id *uint) (*models.Tariff, error) {
+	var tariff models.Tariff
+	var err error
+	if id != nil {
+		err = db.First(&tariff, *id).Error
+	} else {
+		err = db.Where("is_default = ?", true).Order("id ASC").First(&tariff).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoTariff
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tariff, nil
+}
+
+// Quote is a route priced before the ride.
+type Quote struct {
+	Tariff *models.Tariff
+	Trip   Trip
+	Fare   *models.Fare // nil if some stop has no coordinates
+}
+
+// QuoteRoute estimates the fare of a route starting at start with the given
+// tariff, or the one picked by PrepareTrip if tariffID is nil.
+func QuoteRoute(db *gorm.DB, tariffID *uint, stops []models.OrderStop, start time.Time) (*Quote, error) {
+	trip, located := EstimateTrip(stops, start)
+	tariff, err := PrepareTrip(db, tariffID, &trip)
+	if err != nil {
+		return nil, err
+	}
+	q := &Quote{Tariff: tariff, Trip: trip}
+	if located {
+		q.Fare = Calculate(tariff, trip)
+	}
+	return q, nil
+}
+
+// FinalFare prices a finished order with its tariff, or the one picked by
+// PrepareTrip if the order was taken without one.
+func FinalFare(db *gorm.DB, order *models.Order, end time.Time) (*models.Fare, error) {
+	trip, err := ActualTrip(db, order, end)
+	if err != nil {
+		return nil, err
+	}
+	tariff, err := PrepareTrip(db, order.TariffID, &trip)
+	if err != nil {
+		return nil, err
+	}
+	return Calculate(tariff, trip), nil
+}
@@ -0,0 +1,125 @@
+package pricing
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"taxi-fleet-backend/geo"
+	"taxi-fleet-backend/models"
+)
+
+var (
+	// RoadFactor converts straight-line distance between stops into an
+	// approximate road distance.
+	RoadFactor = 1.3
+	// AverageSpeedKmh is the city speed used to estimate trip duration.
+	AverageSpeedKmh = 25.0
+	// maxFixAccuracy drops GPS fixes too imprecise to measure a trip with.
+	maxFixAccuracy = 100.0
+)
+
+// stopPoints returns the coordinates of the route, or false if a stop has none.
+func stopPoints(stops []models.OrderStop) ([]geo.Point, bool) {
+	points := make([]geo.Point, 0, len(stops))
+	for _, s := range stops {
+		if s.Lat == nil || s.Lon == nil {
+			return nil, false
+		}
+		points = append(points, geo.Point{Lat: *s.Lat, Lon: *s.Lon})
+	}
+	return points, len(points) >= 2
+}
+
+func stopPoint(s models.OrderStop) *geo.Point {
+	if s.Lat == nil || s.Lon == nil {
+		return nil
+	}
+	return &geo.Point{Lat: *s.Lat, Lon: *s.Lon}
+}
+
+func pathKm(points []geo.Point) float64 {
+	meters := 0.0
+	for i := 1; i < len(points); i++ {
+		meters += geo.Distance(points[i-1], points[i])
+	}
+	return meters / 1000
+}
+
+// EstimateTrip estimates distance and duration of the route starting at start.
+// It reports false if some stop has no coordinates; the pickup and dropoff
+// points are still set when they are known.
+func EstimateTrip(stops []models.OrderStop, start time.Time) (Trip, bool) {
+	trip := Trip{StartAt: start, Estimated: true}
+	if len(stops) > 0 {
+		trip.Pickup = stopPoint(stops[0])
+		trip.Dropoff = stopPoint(stops[len(stops)-1])
+	}
+	if trip.Pickup != nil {
+		trip.AirportPickup = AtAirport(*trip.Pickup)
+	}
+	if trip.Dropoff != nil {
+		trip.AirportDropoff = AtAirport(*trip.Dropoff)
+	}
+
+	points, ok := stopPoints(stops)
+	if !ok {
+		return trip, false
+	}
+	trip.DistanceKm = pathKm(points) * RoadFactor
+	trip.DurationMin = trip.DistanceKm / AverageSpeedKmh * 60
+	return trip, true
+}
+
+// ActualTrip measures a finished ride: duration from boarding (or the
+// in_progress event for orders without the mark) to end, distance from the
+// driver's GPS track over that time (the route estimate if the track is
+// missing) and waiting from arrival at the pickup until boarding.
+func ActualTrip(db *gorm.DB, order *models.Order, end time.Time) (Trip, error) {
+	start := end
+	if order.PickedUpAt != nil {
+		start = *order.PickedUpAt
+	} else {
+		var started models.OrderEvent
+		err := db.Where("order_id = ? AND to_status = ?", order.ID, models.OrderInProgress).
+			Order("created_at DESC").First(&started).Error
+		switch err {
+		case nil:
+			start = started.CreatedAt
+		case gorm.ErrRecordNotFound:
+		default:
+			return Trip{}, err
+		}
+	}
+
+	trip, _ := EstimateTrip(order.Stops, start)
+	trip.DurationMin = end.Sub(start).Minutes()
+
+	if order.DriverID != nil && end.After(start) {
+		var fixes []models.DriverLocationPoint
+		if err := db.Where("driver_id = ? AND recorded_at BETWEEN ? AND ?", *order.DriverID, start, end).
+			Order("recorded_at ASC").Find(&fixes).Error; err != nil {
+			return Trip{}, err
+		}
+		track := make([]geo.Point, 0, len(fixes))
+		for _, f := range fixes {
+			if f.Accuracy > maxFixAccuracy {
+				continue
+			}
+			track = append(track, geo.Point{Lat: f.Lat, Lon: f.Lon})
+		}
+		if len(track) >= 2 {
+			trip.DistanceKm = pathKm(track)
+			trip.Estimated = false
+		}
+	}
+
+	arrived := order.ArrivedAt
+	if arrived == nil && len(order.Stops) > 0 {
+		arrived = order.Stops[0].ArrivedAt
+	}
+	if arrived != nil && start.After(*arrived) {
+		trip.WaitingMin = start.Sub(*arrived).Minutes()
+	}
+	return trip, nil
+}
@@ -0,0 +1,92 @@
+package pricing
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"taxi-fleet-backend/geo"
+	"taxi-fleet-backend/models"
+)
+
+type cachedShape struct {
+	updatedAt time.Time
+	shape     geo.Shape
+}
+
+// shapes caches parsed zone geometry; an entry is reparsed when the zone changes.
+var shapes = struct {
+	sync.Mutex
+	byZone map[uint]cachedShape
+}{byZone: map[uint]cachedShape{}}
+
+func shapeOf(z *models.Zone) (geo.Shape, error) {
+	shapes.Lock()
+	defer shapes.Unlock()
+	if cached, ok := shapes.byZone[z.ID]; ok && cached.updatedAt.Equal(z.UpdatedAt) {
+		return cached.shape, nil
+	}
+	shape, err := geo.ParseShape(z.Geometry)
+	if err != nil {
+		return nil, err
+	}
+	shapes.byZone[z.ID] = cachedShape{updatedAt: z.UpdatedAt, shape: shape}
+	return shape, nil
+}
+
+// ActiveZones returns the zones used for pricing, highest priority first.
+func ActiveZones(db *gorm.DB) ([]models.Zone, error) {
+	var zones []models.Zone
+	err := db.Where("active = ?", true).Order("priority DESC, id ASC").Find(&zones).Error
+	return zones, err
+}
+
+// ZoneAt returns the first of zones (see ActiveZones) containing p, or nil.
+func ZoneAt(zones []models.Zone, p geo.Point) *models.Zone {
+	for i := range zones {
+		shape, err := shapeOf(&zones[i])
+		if err != nil {
+			log.Printf("pricing: zone %d has invalid geometry: %v", zones[i].ID, err)
+			continue
+		}
+		if shape.Contains(p) {
+			return &zones[i]
+		}
+	}
+	return nil
+}
+
+// PrepareTrip finds the zones of the trip's pickup and dropoff and the fixed
+// price between them, and picks the tariff: the given one, else the pickup
+// zone's, else the default.
+func PrepareTrip(db *gorm.DB, tariffID *uint, trip *Trip) (*models.Tariff, error) {
+	zones, err := ActiveZones(db)
+	if err != nil {
+		return nil, err
+	}
+	if trip.Pickup != nil {
+		trip.PickupZone = ZoneAt(zones, *trip.Pickup)
+	}
+	if trip.Dropoff != nil {
+		trip.DropoffZone = ZoneAt(zones, *trip.Dropoff)
+	}
+
+	if trip.PickupZone != nil && trip.DropoffZone != nil {
+		var fixed models.ZoneFare
+		err := db.Where("from_zone_id = ? AND to_zone_id = ?", trip.PickupZone.ID, trip.DropoffZone.ID).First(&fixed).Error
+		switch err {
+		case nil:
+			trip.FixedPrice = &fixed.Price
+		case gorm.ErrRecordNotFound:
+		default:
+			return nil, err
+		}
+	}
+
+	if tariffID == nil && trip.PickupZone != nil {
+		tariffID = trip.PickupZone.TariffID
+	}
+	return LoadTariff(db, tariffID)
+}
@@ -0,0 +1,252 @@
+package realtime
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"taxi-fleet-backend/models"
+)
+
+type EventType string
+
+const (
+	OrderCreated            EventType = "order.created"
+	OrderAssigned           EventType = "order.assigned"
+	OrderStatusChanged      EventType = "order.status_changed"
+	OrderReminder           EventType = "order.reminder"
+	OrderRescheduled        EventType = "order.rescheduled"
+	OrderReservationDropped EventType = "order.reservation_dropped" // Reserved driver was unavailable at release
+	OrderStopUpdated        EventType = "order.stop_updated"
+	OrderDriverArrived      EventType = "order.driver_arrived"
+	OrderDispatchExhausted  EventType = "order.dispatch_exhausted" // Auto-dispatch has no driver left to offer the order to
+	DriverStatusChanged     EventType = "driver.status_changed"
+	CallIncoming            EventType = "call.incoming"
+	CallEnded               EventType = "call.ended"
+)
+
+// DriverInfo is the driver part of an event payload.
+type DriverInfo struct {
+	ID           uint                `json:"id"`
+	Name         string              `json:"name"`
+	DriverStatus models.DriverStatus `json:"driver_status"`
+}
+
+// CallInfo is the payload of telephony events.
+type CallInfo struct {
+	CallID    string            `json:"call_id"`
+	Caller    string            `json:"caller"`
+	Extension string            `json:"extension"`
+	Customer  *models.Customer  `json:"customer"`
+	Flag      *models.PhoneFlag `json:"flag"`
+	Draft     interface{}       `json:"draft,omitempty"` // Prefilled order form
+}
+
+// Event is a change notification pushed to connected clients.
+type Event struct {
+	ID     uint64        `json:"id"`
+	Type   EventType     `json:"type"`
+	Order  *models.Order `json:"order,omitempty"`
+	Driver *DriverInfo   `json:"driver,omitempty"`
+	Call   *CallInfo     `json:"call,omitempty"`
+	At     time.Time     `json:"at"`
+
+	// audience lists the drivers allowed to see the event. Dispatchers and
+	// system workers see everything unless the event is private.
+	audience []uint
+	private  bool
+}
+
+// NewOrderEvent builds an order event visible to the order's driver and, on
+// reassignment or cancellation, to the driver who lost it. The event holds a
+// copy of the order, so callers may keep changing theirs after publishing.
+func NewOrderEvent(t EventType, order *models.Order, previousDriver *uint) Event {
+	e := Event{Type: t, Order: snapshot(order), At: time.Now()}
+	if order.DriverID != nil {
+		e.audience = append(e.audience, *order.DriverID)
+	}
+	if previousDriver != nil && (order.DriverID == nil || *previousDriver != *order.DriverID) {
+		e.audience = append(e.audience, *previousDriver)
+	}
+	return e
+}
+
+// snapshot copies the order together with its slices and the records it points to.
+func snapshot(order *models.Order) *models.Order {
+	cp := *order
+	cp.Stops = append([]models.OrderStop(nil), order.Stops...)
+	cp.Warnings = append([]string(nil), order.Warnings...)
+	if order.Driver != nil {
+		driver := *order.Driver
+		cp.Driver = &driver
+	}
+	if order.Customer != nil {
+		customer := *order.Customer
+		customer.Addresses = append([]models.CustomerAddress(nil), order.Customer.Addresses...)
+		cp.Customer = &customer
+	}
+	if order.QuotedFare != nil {
+		fare := *order.QuotedFare
+		cp.QuotedFare = &fare
+	}
+	if order.FinalFare != nil {
+		fare := *order.FinalFare
+		cp.FinalFare = &fare
+	}
+	return &cp
+}
+
+// NewDriverStatusEvent builds a driver status event visible to that driver.
+func NewDriverStatusEvent(driver *models.User) Event {
+	return Event{
+		Type:     DriverStatusChanged,
+		Driver:   &DriverInfo{ID: driver.ID, Name: driver.Name, DriverStatus: driver.DriverStatus},
+		At:       time.Now(),
+		audience: []uint{driver.ID},
+	}
+}
+
+// NewCallEvent builds a telephony event visible only to the dispatcher on the call.
+func NewCallEvent(t EventType, dispatcherID uint, call *CallInfo) Event {
+	return Event{
+		Type:     t,
+		Call:     call,
+		At:       time.Now(),
+		audience: []uint{dispatcherID},
+		private:  true,
+	}
+}
+
+// visibleTo reports whether the subscriber may receive the event.
+func (e Event) visibleTo(userID uint, role models.Role) bool {
+	if (role == models.RoleDispatcher || role == models.RoleSystem) && !e.private {
+		return true
+	}
+	for _, id := range e.audience {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	subscriberBuffer = 64
+	// historySize bounds the events kept for Last-Event-ID replay.
+	historySize = 512
+)
+
+// Subscriber receives the events visible to one connected user.
+type Subscriber struct {
+	UserID uint
+	Role   models.Role
+	C      chan Event
+}
+
+// Hub is an in-process pub/sub for order and driver changes. Every published
+// event gets a monotonically increasing ID, and the latest events are kept in a
+// ring buffer so reconnecting clients can catch up.
+type Hub struct {
+	mu        sync.Mutex
+	subs      map[*Subscriber]struct{}
+	listeners map[*listener]struct{}
+	lastID    uint64
+	history   []Event
+	next      int
+}
+
+type listener struct{ fn func(Event) }
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*Subscriber]struct{}), listeners: make(map[*listener]struct{}), history: make([]Event, 0, historySize)}
+}
+
+// DefaultHub is the hub the controllers publish to.
+var DefaultHub = NewHub()
+
+// Subscribe registers a user; call Unsubscribe when the connection closes.
+func (h *Hub) Subscribe(userID uint, role models.Role) *Subscriber {
+	sub, _ := h.SubscribeSince(userID, role, 0)
+	return sub
+}
+
+// SubscribeSince registers a user and returns the buffered events with an ID
+// greater than lastID that the user may see. Registration and the snapshot
+// happen under one lock, so no event falls between the backlog and the channel.
+// A lastID of 0 means no replay.
+func (h *Hub) SubscribeSince(userID uint, role models.Role, lastID uint64) (*Subscriber, []Event) {
+	sub := &Subscriber{UserID: userID, Role: role, C: make(chan Event, subscriberBuffer)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var backlog []Event
+	if lastID > 0 {
+		for i := range h.history {
+			e := h.history[(h.next+i)%len(h.history)]
+			if e.ID > lastID && e.visibleTo(userID, role) {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+	h.subs[sub] = struct{}{}
+	return sub, backlog
+}
+
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mu.Lock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.C)
+	}
+	h.mu.Unlock()
+}
+
+// Listen calls fn with every published event, in order and without drops,
+// for in-process consumers that must not miss one. fn runs while the hub is
+// locked, so it must return quickly and must not publish. The returned func
+// stops the calls.
+func (h *Hub) Listen(fn func(Event)) (stop func()) {
+	l := &listener{fn: fn}
+	h.mu.Lock()
+	h.listeners[l] = struct{}{}
+	h.mu.Unlock()
+	return func() {
+		h.mu.Lock()
+		delete(h.listeners, l)
+		h.mu.Unlock()
+	}
+}
+
+// Publish delivers the event to every listener and to every subscriber allowed
+// to see it. Slow subscribers whose buffer is full miss the event rather than
+// block the caller.
+func (h *Hub) Publish(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	e.ID = h.lastID
+	if len(h.history) < historySize {
+		h.history = append(h.history, e)
+	} else {
+		h.history[h.next] = e
+		h.next = (h.next + 1) % historySize
+	}
+
+	for l := range h.listeners {
+		l.fn(e)
+	}
+	for sub := range h.subs {
+		if !e.visibleTo(sub.UserID, sub.Role) {
+			continue
+		}
+		select {
+		case sub.C <- e:
+		default:
+			log.Printf("realtime: dropping %s for user %d, buffer full", e.Type, sub.UserID)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/dispatch"
+	"taxi-fleet-backend/models"
+	"taxi-fleet-backend/notify"
+	"taxi-fleet-backend/realtime"
+)
+
+const (
+	defaultLeadTime     = 30 * time.Minute
+	defaultReminderLead = 60 * time.Minute
+	tickInterval        = 30 * time.Second
+)
+
+// Config controls when scheduled orders go live.
+type Config struct {
+	// LeadTime before pickup at which a scheduled order enters the live queue.
+	LeadTime time.Duration
+	// ReminderLead before pickup at which the reserved driver is reminded.
+	ReminderLead time.Duration
+}
+
+// ConfigFromEnv reads SCHEDULED_ORDER_LEAD_MINUTES and SCHEDULED_ORDER_REMINDER_MINUTES.
+func ConfigFromEnv() Config {
+	cfg := Config{LeadTime: defaultLeadTime, ReminderLead: defaultReminderLead}
+	if v, err := strconv.Atoi(os.Getenv("SCHEDULED_ORDER_LEAD_MINUTES")); err == nil && v >= 0 {
+		cfg.LeadTime = time.Duration(v) * time.Minute
+	}
+	if v, err := strconv.Atoi(os.Getenv("SCHEDULED_ORDER_REMINDER_MINUTES")); err == nil && v >= 0 {
+		cfg.ReminderLead = time.Duration(v) * time.Minute
+	}
+	return cfg
+}
+
+// Scheduler generates orders from recurring templates, promotes pre-booked
+// orders to the live queue and reminds reserved drivers.
+type Scheduler struct {
+	cfg Config
+}
+
+func New(cfg Config) *Scheduler {
+	return &Scheduler{cfg: cfg}
+}
+
+// Run checks scheduled orders until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		s.tick()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick() {
+	now := time.Now()
+
+	s.generateAll(now)
+
+	if err := s.remind(now); err != nil {
+		log.Printf("scheduler: reminders: %v", err)
+	}
+
+	var ids []uint
+	err := database.DB.Model(&models.Order{}).
+		Where("status = ? AND scheduled_at <= ?", models.OrderScheduled, now.Add(s.cfg.LeadTime)).
+		Order("scheduled_at ASC").
+		Pluck("id", &ids).Error
+	if err != nil {
+		log.Printf("scheduler: loading due orders: %v", err)
+		return
+	}
+	promoted := false
+	for _, id := range ids {
+		ok, err := s.promote(id)
+		if err != nil {
+			log.Printf("scheduler: promoting order %d: %v", id, err)
+			continue
+		}
+		promoted = promoted || ok
+	}
+	if promoted {
+		dispatch.DefaultEngine.Notify()
+	}
+}
+
+// promote moves a scheduled order to the reserved driver, or to the queue if
+// there is none or the driver is offline or busy with another order.
+func (s *Scheduler) promote(id uint) (bool, error) {
+	var order models.Order
+	var dropped *uint
+	promoted := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+			return err
+		}
+		if order.Status != models.OrderScheduled {
+			return nil
+		}
+		reserved := order.DriverID
+		note := "scheduled order released"
+		if reserved != nil {
+			_, err := dispatch.LockAvailableDriver(tx, *order.DriverID, order.ID)
+			var unavailable *dispatch.DriverUnavailableError
+			switch {
+			case errors.As(err, &unavailable):
+				dropped = order.DriverID
+				order.DriverID = nil
+				note = fmt.Sprintf("scheduled order released to the queue: reserved %v", unavailable)
+			case err != nil:
+				return err
+			}
+		}
+		to := models.OrderNew
+		if order.DriverID != nil {
+			to = models.OrderAssigned
+		}
+		if err := models.OrderFlow.Apply(&order, models.RoleSystem, to); err != nil {
+			return err
+		}
+		if err := tx.Save(&order).Error; err != nil {
+			return err
+		}
+		promoted = true
+		return tx.Create(models.NewOrderEvent(&order, 0, models.RoleSystem, models.OrderScheduled, reserved, note)).Error
+	})
+	if err != nil || !promoted {
+		return false, err
+	}
+
+	if dropped != nil {
+		log.Printf("scheduler: order %d released without its reserved driver %d", order.ID, *dropped)
+		realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderReservationDropped, &order, dropped))
+		return true, nil
+	}
+
+	if order.DriverID == nil {
+		realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderStatusChanged, &order, nil))
+		return true, nil
+	}
+	realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderAssigned, &order, nil))
+	notify.DefaultPusher.NotifyDriver(*order.DriverID, notify.PushAssigned, &order)
+	return true, nil
+}
+
+// remind notifies reserved drivers once, ReminderLead before pickup.
+func (s *Scheduler) remind(now time.Time) error {
+	var orders []models.Order
+	err := database.DB.
+		Where("status = ? AND driver_id IS NOT NULL AND reminded_at IS NULL AND scheduled_at <= ?",
+			models.OrderScheduled, now.Add(s.cfg.ReminderLead)).
+		Find(&orders).Error
+	if err != nil {
+		return err
+	}
+	for i := range orders {
+		order := &orders[i]
+		res := database.DB.Model(&models.Order{}).
+			Where("id = ? AND reminded_at IS NULL", order.ID).
+			UpdateColumn("reminded_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		order.RemindedAt = &now
+		realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderReminder, order, nil))
+		notify.DefaultPusher.NotifyDriver(*order.DriverID, notify.PushReminder, order)
+	}
+	return nil
+}
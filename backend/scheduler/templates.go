@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"taxi-fleet-backend/database"
+	"taxi-fleet-backend/geo"
+	"taxi-fleet-backend/models"
+	"taxi-fleet-backend/pricing"
+	"taxi-fleet-backend/realtime"
+)
+
+const defaultTemplateHorizon = 7 * 24 * time.Hour
+
+// TemplateHorizon is how far ahead orders are generated from recurring
+// templates, ORDER_TEMPLATE_HORIZON_DAYS (default 7).
+func TemplateHorizon() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("ORDER_TEMPLATE_HORIZON_DAYS")); err == nil && v > 0 {
+		return time.Duration(v) * 24 * time.Hour
+	}
+	return defaultTemplateHorizon
+}
+
+// generateAll materializes upcoming occurrences of every active template.
+func (s *Scheduler) generateAll(now time.Time) {
+	var ids []uint
+	if err := database.DB.Model(&models.OrderTemplate{}).Where("paused = ?", false).Pluck("id", &ids).Error; err != nil {
+		log.Printf("scheduler: loading templates: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if _, err := GenerateFromTemplate(id, now); err != nil {
+			log.Printf("scheduler: template %d: %v", id, err)
+		}
+	}
+}
+
+// GenerateFromTemplate creates scheduled orders for the template's occurrences
+// between its last generated one (or now) and the horizon. The orders are
+// geocoded and quoted like the ones dispatchers create. It returns the new orders.
+func GenerateFromTemplate(templateID uint, now time.Time) ([]models.Order, error) {
+	var draft models.OrderTemplate
+	if err := database.DB.First(&draft, templateID).Error; err != nil {
+		return nil, err
+	}
+	if draft.Paused {
+		return nil, nil
+	}
+	// Every occurrence has the same route, so it is geocoded once, outside the
+	// transaction that holds the template lock.
+	route := models.SimpleRoute(draft.FromAddress, draft.ToAddress, draft.PickupLat, draft.PickupLon)
+	geo.LocateStops(context.Background(), route)
+
+	var created []models.Order
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var tpl models.OrderTemplate
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tpl, templateID).Error; err != nil {
+			return err
+		}
+		if tpl.Paused {
+			return nil
+		}
+		if !sameRoute(&tpl, &draft) {
+			// Edited while the route was being geocoded; the next run picks it up.
+			return nil
+		}
+
+		after := now
+		if tpl.GeneratedUntil != nil && tpl.GeneratedUntil.After(after) {
+			after = *tpl.GeneratedUntil
+		}
+		occurrences, err := tpl.Occurrences(after, now.Add(TemplateHorizon()))
+		if err != nil {
+			return err
+		}
+		if len(occurrences) == 0 {
+			return nil
+		}
+
+		driverID, err := templateDriver(tx, &tpl)
+		if err != nil {
+			return err
+		}
+
+		for _, at := range occurrences {
+			scheduledAt := at
+			order := models.Order{
+				Comment:     tpl.Comment,
+				DriverID:    driverID,
+				ScheduledAt: &scheduledAt,
+				TemplateID:  &tpl.ID,
+				Stops:       append([]models.OrderStop(nil), route...),
+			}
+			if err := pricing.QuoteOrder(tx, &order, nil); err != nil {
+				return err
+			}
+			if err := models.OrderFlow.Apply(&order, models.RoleSystem, models.OrderScheduled); err != nil {
+				return err
+			}
+			if err := tx.Create(&order).Error; err != nil {
+				return err
+			}
+			note := fmt.Sprintf("generated from template #%d", tpl.ID)
+			if err := tx.Create(models.NewOrderEvent(&order, 0, models.RoleSystem, "", nil, note)).Error; err != nil {
+				return err
+			}
+			created = append(created, order)
+		}
+
+		last := occurrences[len(occurrences)-1]
+		tpl.GeneratedUntil = &last
+		return tx.Model(&tpl).UpdateColumn("generated_until", last).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range created {
+		realtime.DefaultHub.Publish(realtime.NewOrderEvent(realtime.OrderCreated, &created[i], nil))
+	}
+	return created, nil
+}
+
+// sameRoute reports whether two versions of a template describe the same route.
+func sameRoute(a, b *models.OrderTemplate) bool {
+	sameCoord := func(x, y *float64) bool {
+		return x == nil && y == nil || x != nil && y != nil && *x == *y
+	}
+	return a.FromAddress == b.FromAddress && a.ToAddress == b.ToAddress &&
+		sameCoord(a.PickupLat, b.PickupLat) && sameCoord(a.PickupLon, b.PickupLon)
+}
+
+// templateDriver returns the template's reserved driver, or nil if that user is
+// gone or no longer a driver; the orders are then generated for the queue.
+func templateDriver(tx *gorm.DB, tpl *models.OrderTemplate) (*uint, error) {
+	if tpl.DriverID == nil {
+		return nil, nil
+	}
+	var driver models.User
+	err := tx.First(&driver, *tpl.DriverID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && driver.Role != models.RoleDriver) {
+		log.Printf("scheduler: template %d: reserved driver %d is not a driver, generating without one", tpl.ID, *tpl.DriverID)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &driver.ID, nil
+}
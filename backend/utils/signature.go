@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SignWebhook returns the hex HMAC-SHA256 of "<timestamp>.<body>" with secret.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks signature against SignWebhook in constant time.
+func VerifyWebhook(secret, timestamp string, body []byte, signature string) bool {
+	expected := SignWebhook(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -1,13 +1,29 @@
 import 'package:flutter/material.dart';
 import 'dart:async';
+import 'dart:convert';
+import 'package:jwt_decoder/jwt_decoder.dart';
+import 'package:web_socket_channel/web_socket_channel.dart';
 import '../services/api_service.dart';
 
+// Statuses shown on the live boards, same as the default of GET /api/orders;
+// drivers also get the scheduled orders reserved for them
+const _liveStatuses = {'new', 'assigned', 'accepted', 'in_progress'};
+
 class OrderProvider with ChangeNotifier {
   final ApiService _apiService = ApiService();
 
   List<dynamic> _drivers = [];
   List<dynamic> _orders = [];
-  Timer? _pollingTimer;
+  WebSocketChannel? _channel;
+  StreamSubscription? _subscription;
+  Timer? _reconnectTimer;
+  Duration _reconnectDelay = _minReconnectDelay;
+  bool _active = false;
+  int? _userId;
+  String? _role;
+
+  static const _minReconnectDelay = Duration(seconds: 2);
+  static const _maxReconnectDelay = Duration(seconds: 30);
 
   List<dynamic> get drivers => _drivers;
   List<dynamic> get orders => _orders;
@@ -27,15 +43,116 @@ class OrderProvider with ChangeNotifier {
     return _orders.where((o) => o['status'] == 'assigned').toList();
   }
 
-  void startPolling() {
-    _fetchData();
-    _pollingTimer = Timer.periodic(Duration(seconds: 5), (timer) {
-      _fetchData();
+  // Loads the lists once and then keeps them current from the updates socket
+  void startUpdates() {
+    if (_active) return;
+    _active = true;
+    _connect();
+  }
+
+  void stopUpdates() {
+    _active = false;
+    _reconnectTimer?.cancel();
+    _subscription?.cancel();
+    _channel?.sink.close();
+    _subscription = null;
+    _channel = null;
+  }
+
+  // Full reload, e.g. pull-to-refresh or after a change made elsewhere
+  Future<void> refresh() => _fetchData();
+
+  Future<void> _connect() async {
+    try {
+      final token = await _apiService.getToken();
+      if (token != null) {
+        final claims = JwtDecoder.decode(token);
+        _userId = (claims['user_id'] as num?)?.toInt();
+        _role = claims['role'] as String?;
+      }
+
+      final channel = WebSocketChannel.connect(await _apiService.updatesSocketUri());
+      await channel.ready;
+      if (!_active) {
+        channel.sink.close();
+        return;
+      }
+      _channel = channel;
+      _subscription = channel.stream.listen(
+        _onMessage,
+        onDone: _scheduleReconnect,
+        onError: (e) {
+          debugPrint('Updates socket error: $e');
+          _scheduleReconnect();
+        },
+        cancelOnError: true,
+      );
+      _reconnectDelay = _minReconnectDelay;
+    } catch (e) {
+      debugPrint('Updates socket connect failed: $e');
+      _scheduleReconnect();
+    }
+    // Catch up on whatever changed while we were not connected
+    await _fetchData();
+  }
+
+  void _scheduleReconnect() {
+    _subscription = null;
+    _channel = null;
+    if (!_active || (_reconnectTimer?.isActive ?? false)) return;
+    _reconnectTimer = Timer(_reconnectDelay, () {
+      if (_active) _connect();
     });
+    _reconnectDelay *= 2;
+    if (_reconnectDelay > _maxReconnectDelay) {
+      _reconnectDelay = _maxReconnectDelay;
+    }
   }
 
-  void stopPolling() {
-    _pollingTimer?.cancel();
+  void _onMessage(dynamic message) {
+    final Map<String, dynamic> event;
+    try {
+      event = jsonDecode(message as String) as Map<String, dynamic>;
+    } catch (_) {
+      return;
+    }
+
+    final order = event['order'];
+    if (order is Map<String, dynamic>) {
+      _applyOrder(order);
+      notifyListeners();
+    }
+    final driver = event['driver'];
+    if (event['type'] == 'driver.status_changed' && driver is Map<String, dynamic>) {
+      for (final d in _drivers) {
+        if (d['id'] == driver['id']) {
+          d['driver_status'] = driver['driver_status'];
+        }
+      }
+      notifyListeners();
+    }
+  }
+
+  // Inserts, replaces or drops the order depending on whether it still belongs on this board
+  void _applyOrder(Map<String, dynamic> order) {
+    final index = _orders.indexWhere((o) => o['id'] == order['id']);
+    final status = order['status'];
+    final visible = _role == 'driver'
+        ? order['driver_id'] == _userId &&
+            (_liveStatuses.contains(status) || status == 'scheduled')
+        : _liveStatuses.contains(status);
+    if (!visible) {
+      if (index >= 0) _orders.removeAt(index);
+      return;
+    }
+    if (index >= 0) {
+      // Events may arrive after a newer copy was fetched
+      final known = (_orders[index]['version'] as num?)?.toInt() ?? 0;
+      final incoming = (order['version'] as num?)?.toInt() ?? 0;
+      if (incoming >= known) _orders[index] = order;
+    } else {
+      _orders.insert(0, order);
+    }
   }
 
   Future<void> _fetchData() async {
@@ -56,7 +173,7 @@ class OrderProvider with ChangeNotifier {
         // Ignore if not dispatcher
       }
     } catch (e) {
-      debugPrint('Polling error: $e');
+      debugPrint('Fetch error: $e');
     }
   }
 
@@ -76,13 +193,29 @@ class OrderProvider with ChangeNotifier {
     // Refresh might be needed?
   }
 
+  // Version of the order as last fetched; the server rejects changes to stale copies
+  int? _versionOf(dynamic orderId) {
+    for (final o in _orders) {
+      if (o['id'].toString() == orderId.toString()) {
+        return (o['version'] as num?)?.toInt();
+      }
+    }
+    return null;
+  }
+
   Future<void> updateOrderStatus(int orderId, String status) async {
-    await _apiService.updateOrderStatus(orderId, status);
-    await _fetchData();
+    try {
+      await _apiService.updateOrderStatus(orderId, status, version: _versionOf(orderId));
+    } finally {
+      await _fetchData();
+    }
   }
 
   Future<void> assignOrderDriver(dynamic orderId, int driverId) async {
-    await _apiService.assignOrderDriver(orderId, driverId);
-    await _fetchData();
+    try {
+      await _apiService.assignOrderDriver(orderId, driverId, version: _versionOf(orderId));
+    } finally {
+      await _fetchData();
+    }
   }
 }
@@ -51,12 +51,12 @@ class _DispatcherHomeScreenState extends State<DispatcherHomeScreen> {
   @override
   void initState() {
     super.initState();
-    Provider.of<OrderProvider>(context, listen: false).startPolling();
+    Provider.of<OrderProvider>(context, listen: false).startUpdates();
   }
 
   @override
   void dispose() {
-    Provider.of<OrderProvider>(context, listen: false).stopPolling();
+    Provider.of<OrderProvider>(context, listen: false).stopUpdates();
     super.dispose();
   }
 
@@ -173,7 +173,7 @@ class _DispatcherHomeScreenState extends State<DispatcherHomeScreen> {
                             if (context.mounted) {
                               Navigator.pop(ctx);
                               Provider.of<OrderProvider>(context, listen: false)
-                                  .startPolling();
+                                  .refresh();
                               ScaffoldMessenger.of(context).showSnackBar(
                                 SnackBar(
                                   content: Text(l10n.driverCreated),
@@ -618,9 +618,7 @@ class _DispatcherHomeScreenState extends State<DispatcherHomeScreen> {
       ),
       body: RefreshIndicator(
         onRefresh: () async {
-          provider.stopPolling();
-          await Future.delayed(const Duration(milliseconds: 500));
-          provider.startPolling();
+          await provider.refresh();
         },
         child: SingleChildScrollView(
           physics: const AlwaysScrollableScrollPhysics(),
@@ -22,12 +22,12 @@ class _DriverHomeScreenState extends State<DriverHomeScreen> {
   @override
   void initState() {
     super.initState();
-    Provider.of<OrderProvider>(context, listen: false).startPolling();
+    Provider.of<OrderProvider>(context, listen: false).startUpdates();
   }
 
   @override
   void dispose() {
-    Provider.of<OrderProvider>(context, listen: false).stopPolling();
+    Provider.of<OrderProvider>(context, listen: false).stopUpdates();
     super.dispose();
   }
 
@@ -19,6 +19,16 @@ class ApiService {
     };
   }
 
+  // WebSocket with live order and driver updates. Browsers can't set headers
+  // on a WebSocket, so the token goes in the query string.
+  Future<Uri> updatesSocketUri() async {
+    final token = await getToken();
+    return Uri.parse(baseUrl.replaceFirst('http', 'ws')).replace(
+      path: '/api/ws',
+      queryParameters: {if (token != null) 'token': token},
+    );
+  }
+
   // Auth
   Future<Map<String, dynamic>> login(String phone, String password) async {
     final response = await http.post(
@@ -153,7 +163,7 @@ class ApiService {
     return jsonDecode(response.body) as Map<String, dynamic>;
   }
 
-  Future<void> assignOrderDriver(dynamic orderId, int driverId) async {
+  Future<void> assignOrderDriver(dynamic orderId, int driverId, {int? version}) async {
     // Handle different types of orderId
     int id;
     if (orderId is int) {
@@ -169,7 +179,10 @@ class ApiService {
     final response = await http.put(
       Uri.parse('$baseUrl/api/orders/$id/assign'),
       headers: await _getHeaders(),
-      body: jsonEncode({'driver_id': driverId}),
+      body: jsonEncode({
+        'driver_id': driverId,
+        if (version != null) 'version': version,
+      }),
     );
 
     if (response.statusCode != 200) {
@@ -177,11 +190,14 @@ class ApiService {
     }
   }
 
-  Future<void> updateOrderStatus(int orderId, String status) async {
+  Future<void> updateOrderStatus(int orderId, String status, {int? version}) async {
     final response = await http.put(
       Uri.parse('$baseUrl/api/orders/$orderId/status'),
       headers: await _getHeaders(),
-      body: jsonEncode({'status': status}),
+      body: jsonEncode({
+        'status': status,
+        if (version != null) 'version': version,
+      }),
     );
 
     if (response.statusCode != 200) {